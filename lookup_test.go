@@ -0,0 +1,313 @@
+// Copyright 2020 igrmk. All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to writing, software distributed
+// under the License is distributed on a "AS IS" BASIS, WITHOUT WARRANTIES OR
+// CONDITIONS OF ANY KIND, either express or implied.
+//
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+)
+
+// The benchmarks below compare looking up the constants of a 50-value enum
+// in maps, as the generated code does by default, with looking them up in
+// switches and in sorted arrays, as -lookup=switch and -lookup=binary do.
+
+const benchValues = 50
+
+var (
+	benchNameToValue = make(map[string]int, benchValues)
+	benchValueToName = make(map[int]string, benchValues)
+	benchNames       []string
+	// benchSortedNames and benchSortedValues are sorted already as the
+	// names are zero-padded.
+	benchSortedNames  [benchValues]string
+	benchSortedValues [benchValues]int
+)
+
+func init() {
+	for i := 0; i < benchValues; i++ {
+		name := fmt.Sprintf("Value%02d", i)
+		benchNameToValue[name] = i
+		benchValueToName[i] = name
+		benchNames = append(benchNames, name)
+		benchSortedNames[i] = name
+		benchSortedValues[i] = i
+	}
+}
+
+func benchSwitchValue(s string) (int, bool) {
+	switch s {
+	case "Value00":
+		return 0, true
+	case "Value01":
+		return 1, true
+	case "Value02":
+		return 2, true
+	case "Value03":
+		return 3, true
+	case "Value04":
+		return 4, true
+	case "Value05":
+		return 5, true
+	case "Value06":
+		return 6, true
+	case "Value07":
+		return 7, true
+	case "Value08":
+		return 8, true
+	case "Value09":
+		return 9, true
+	case "Value10":
+		return 10, true
+	case "Value11":
+		return 11, true
+	case "Value12":
+		return 12, true
+	case "Value13":
+		return 13, true
+	case "Value14":
+		return 14, true
+	case "Value15":
+		return 15, true
+	case "Value16":
+		return 16, true
+	case "Value17":
+		return 17, true
+	case "Value18":
+		return 18, true
+	case "Value19":
+		return 19, true
+	case "Value20":
+		return 20, true
+	case "Value21":
+		return 21, true
+	case "Value22":
+		return 22, true
+	case "Value23":
+		return 23, true
+	case "Value24":
+		return 24, true
+	case "Value25":
+		return 25, true
+	case "Value26":
+		return 26, true
+	case "Value27":
+		return 27, true
+	case "Value28":
+		return 28, true
+	case "Value29":
+		return 29, true
+	case "Value30":
+		return 30, true
+	case "Value31":
+		return 31, true
+	case "Value32":
+		return 32, true
+	case "Value33":
+		return 33, true
+	case "Value34":
+		return 34, true
+	case "Value35":
+		return 35, true
+	case "Value36":
+		return 36, true
+	case "Value37":
+		return 37, true
+	case "Value38":
+		return 38, true
+	case "Value39":
+		return 39, true
+	case "Value40":
+		return 40, true
+	case "Value41":
+		return 41, true
+	case "Value42":
+		return 42, true
+	case "Value43":
+		return 43, true
+	case "Value44":
+		return 44, true
+	case "Value45":
+		return 45, true
+	case "Value46":
+		return 46, true
+	case "Value47":
+		return 47, true
+	case "Value48":
+		return 48, true
+	case "Value49":
+		return 49, true
+	}
+	return 0, false
+}
+
+func benchSwitchName(v int) (string, bool) {
+	switch v {
+	case 0:
+		return "Value00", true
+	case 1:
+		return "Value01", true
+	case 2:
+		return "Value02", true
+	case 3:
+		return "Value03", true
+	case 4:
+		return "Value04", true
+	case 5:
+		return "Value05", true
+	case 6:
+		return "Value06", true
+	case 7:
+		return "Value07", true
+	case 8:
+		return "Value08", true
+	case 9:
+		return "Value09", true
+	case 10:
+		return "Value10", true
+	case 11:
+		return "Value11", true
+	case 12:
+		return "Value12", true
+	case 13:
+		return "Value13", true
+	case 14:
+		return "Value14", true
+	case 15:
+		return "Value15", true
+	case 16:
+		return "Value16", true
+	case 17:
+		return "Value17", true
+	case 18:
+		return "Value18", true
+	case 19:
+		return "Value19", true
+	case 20:
+		return "Value20", true
+	case 21:
+		return "Value21", true
+	case 22:
+		return "Value22", true
+	case 23:
+		return "Value23", true
+	case 24:
+		return "Value24", true
+	case 25:
+		return "Value25", true
+	case 26:
+		return "Value26", true
+	case 27:
+		return "Value27", true
+	case 28:
+		return "Value28", true
+	case 29:
+		return "Value29", true
+	case 30:
+		return "Value30", true
+	case 31:
+		return "Value31", true
+	case 32:
+		return "Value32", true
+	case 33:
+		return "Value33", true
+	case 34:
+		return "Value34", true
+	case 35:
+		return "Value35", true
+	case 36:
+		return "Value36", true
+	case 37:
+		return "Value37", true
+	case 38:
+		return "Value38", true
+	case 39:
+		return "Value39", true
+	case 40:
+		return "Value40", true
+	case 41:
+		return "Value41", true
+	case 42:
+		return "Value42", true
+	case 43:
+		return "Value43", true
+	case 44:
+		return "Value44", true
+	case 45:
+		return "Value45", true
+	case 46:
+		return "Value46", true
+	case 47:
+		return "Value47", true
+	case 48:
+		return "Value48", true
+	case 49:
+		return "Value49", true
+	}
+	return "", false
+}
+
+func BenchmarkNameToValueMap(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		if _, ok := benchNameToValue[benchNames[i%benchValues]]; !ok {
+			b.Fatal("not found")
+		}
+	}
+}
+
+func BenchmarkNameToValueSwitch(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		if _, ok := benchSwitchValue(benchNames[i%benchValues]); !ok {
+			b.Fatal("not found")
+		}
+	}
+}
+
+func BenchmarkNameToValueBinary(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		s := benchNames[i%benchValues]
+		j := sort.SearchStrings(benchSortedNames[:], s)
+		if j == len(benchSortedNames) || benchSortedNames[j] != s || benchSortedValues[j] != i%benchValues {
+			b.Fatal("not found")
+		}
+	}
+}
+
+// BenchmarkNameToValueInit measures building the map the generated code
+// builds at init by default. Sorted arrays of constants need no building.
+func BenchmarkNameToValueInit(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		m := make(map[string]int)
+		for j, name := range benchNames {
+			m[name] = j
+		}
+	}
+}
+
+func BenchmarkValueToNameMap(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		if _, ok := benchValueToName[i%benchValues]; !ok {
+			b.Fatal("not found")
+		}
+	}
+}
+
+func BenchmarkValueToNameSwitch(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		if _, ok := benchSwitchName(i % benchValues); !ok {
+			b.Fatal("not found")
+		}
+	}
+}
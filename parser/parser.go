@@ -13,17 +13,22 @@
 // limitations under the License.
 
 // Package parser parses Go code and keeps track of all the types defined
-// and provides access to all the constants defined for an int type.
+// and provides access to all the constants defined for an int or string type.
 package parser
 
 import (
+	"errors"
 	"fmt"
 	"go/ast"
 	"go/build"
 	"go/constant"
+	"go/parser"
+	"go/scanner"
 	"go/token"
 	"go/types"
-	"log"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 
 	"golang.org/x/tools/go/loader"
@@ -33,37 +38,354 @@ import (
 type Package struct {
 	Name  string
 	files []*ast.File
+	fset  *token.FileSet
+	// file is the base name of the only file to take constants from, if
+	// set.
+	file string
 
-	defs map[*ast.Ident]types.Object
+	defs      map[*ast.Ident]types.Object
+	implicits map[ast.Node]types.Object
+	scope     *types.Scope
+	// errs are the errors loading the package ignored with BestEffort.
+	errs []error
 }
 
-// ParsePackage parses the package in the given directory and returns it.
+// A Value is a constant declared with the type it was looked up for.
+type Value struct {
+	// Name is the name of the constant.
+	Name string
+	// Value is what the constant evaluates to.
+	Value constant.Value
+	// Comment is the text of the line comment following the constant,
+	// if any.
+	Comment string
+	// Doc is the text of the doc comment preceding the constant, if any.
+	Doc string
+	// Override is the string given by a yamlenums:"string" comment of the
+	// constant, if any.
+	Override string
+}
+
+// overridePrefix starts the comments overriding the strings of constants.
+const overridePrefix = "yamlenums:"
+
+// Config specifies how packages are parsed. The zero value parses the files
+// a regular build of the package would use.
+type Config struct {
+	// Tests makes the _test.go files of the package parsed as well.
+	Tests bool
+	// Tags lists the build tags to consider satisfied, in addition to the
+	// ones of the current platform.
+	Tags []string
+	// File is the base name of the only file of the package whose constants
+	// are taken into account, such as the $GOFILE set by go generate. The
+	// other files are still parsed to type-check the package.
+	File string
+	// BestEffort makes the package parsed even if it has errors, as long as
+	// none of them is in the declarations of the constants looked up.
+	BestEffort bool
+}
+
+// ParsePackage parses the package in the given directory using the zero
+// Config and returns it.
 func ParsePackage(directory string) (*Package, error) {
-	p, err := build.ImportDir(directory, build.FindOnly)
+	return (&Config{}).ParsePackage(directory)
+}
+
+// ParsePackage parses the package in the given directory and returns it.
+func (c *Config) ParsePackage(directory string) (*Package, error) {
+	ctxt := build.Default
+	ctxt.BuildTags = c.Tags
+	// In module mode the imports, such as other packages of the module
+	// holding constants the values refer to, are resolved by go list, which
+	// must run in the module rather than the current directory. go/build
+	// rejects relative source directories once Dir is set, so it's only set
+	// for absolute paths.
+	if filepath.IsAbs(directory) {
+		ctxt.Dir = directory
+	}
+	p, err := ctxt.ImportDir(directory, build.FindOnly)
 	if err != nil {
 		return nil, fmt.Errorf("provided directory (%s) may not under GOPATH (%s): %v",
 			directory, build.Default.GOPATH, err)
 	}
 
-	conf := loader.Config{TypeChecker: types.Config{FakeImportC: true}}
-	conf.Import(p.ImportPath)
-	program, err := conf.Load()
+	conf := loader.Config{
+		Build: &ctxt,
+		// Outside of GOPATH the import path is relative to the directory.
+		Cwd:         directory,
+		ParserMode:  parser.ParseComments,
+		TypeChecker: types.Config{FakeImportC: true},
+	}
+	if c.Tests {
+		conf.ImportWithTests(p.ImportPath)
+	} else {
+		conf.Import(p.ImportPath)
+	}
+	program, err := c.load(&conf)
 	if err != nil {
 		return nil, fmt.Errorf("couldn't load package: %v", err)
 	}
 
-	pkgInfo := program.Package(p.ImportPath)
-	return &Package{
-		Name:  pkgInfo.Pkg.Name(),
-		files: pkgInfo.Files,
-		defs:  pkgInfo.Defs,
-	}, nil
+	return c.newPackage(program, program.Package(p.ImportPath))
+}
+
+// ParseFiles parses the given Go files as a single package using the zero
+// Config and returns it.
+func ParseFiles(files []string) (*Package, error) {
+	return (&Config{}).ParseFiles(files)
+}
+
+// ParseFiles parses the given Go files as a single package and returns it.
+// The files are parsed regardless of their names and build constraints, so
+// Tests and Tags don't apply.
+func (c *Config) ParseFiles(files []string) (*Package, error) {
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files to parse")
+	}
+	ctxt := build.Default
+	if filepath.IsAbs(files[0]) {
+		ctxt.Dir = filepath.Dir(files[0])
+	}
+	conf := loader.Config{
+		Build: &ctxt,
+		// The imports of the files are relative to their directory.
+		Cwd:         filepath.Dir(files[0]),
+		ParserMode:  parser.ParseComments,
+		TypeChecker: types.Config{FakeImportC: true},
+	}
+	conf.CreateFromFilenames("command-line-arguments", files...)
+	program, err := c.load(&conf)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't load files: %v", err)
+	}
+	return c.newPackage(program, program.Created[0])
+}
+
+// load loads the program conf describes. Type errors are returned rather than
+// printed, so the error tells which code, such as the value of a constant,
+// failed to check. With BestEffort they are kept for ValuesOfType to check
+// instead.
+func (c *Config) load(conf *loader.Config) (*loader.Program, error) {
+	var typeErrs []string
+	conf.TypeChecker.Error = func(err error) { typeErrs = append(typeErrs, err.Error()) }
+	conf.AllowErrors = c.BestEffort
+	program, err := conf.Load()
+	if err != nil && len(typeErrs) > 0 {
+		return nil, errors.New(strings.Join(typeErrs, "\n\t"))
+	}
+	return program, err
+}
+
+// newPackage returns the Package of the loaded pkgInfo.
+func (c *Config) newPackage(program *loader.Program, pkgInfo *loader.PackageInfo) (*Package, error) {
+	pkg := &Package{
+		Name:      pkgInfo.Pkg.Name(),
+		files:     pkgInfo.Files,
+		fset:      program.Fset,
+		file:      c.File,
+		defs:      pkgInfo.Defs,
+		implicits: pkgInfo.Implicits,
+		scope:     pkgInfo.Pkg.Scope(),
+		errs:      pkgInfo.Errors,
+	}
+	// Constants are collected file by file, so sorting the files by name
+	// keeps the order of constants spread across files stable.
+	sort.SliceStable(pkg.files, func(i, j int) bool {
+		return pkg.fset.File(pkg.files[i].Pos()).Name() < pkg.fset.File(pkg.files[j].Pos()).Name()
+	})
+	if len(c.File) > 0 {
+		found := false
+		for _, file := range pkg.files {
+			found = found || pkg.inFile(file.Pos())
+		}
+		if !found {
+			return nil, fmt.Errorf("file %s not found in package %s", c.File, pkg.Name)
+		}
+	}
+	return pkg, nil
+}
+
+// Errors returns the errors ignored loading the package with BestEffort.
+func (pkg *Package) Errors() []error {
+	return pkg.errs
+}
+
+// errorsIn returns the ignored errors located in node.
+func (pkg *Package) errorsIn(node ast.Node) []string {
+	start, end := pkg.fset.Position(node.Pos()), pkg.fset.Position(node.End())
+	var errs []string
+	for _, err := range pkg.errs {
+		for _, pos := range errorPositions(pkg.fset, err) {
+			if pos.Filename == start.Filename && pos.Offset >= start.Offset && pos.Offset < end.Offset {
+				errs = append(errs, err.Error())
+				break
+			}
+		}
+	}
+	return errs
+}
+
+// errorPositions returns the positions of err, which may be a list of
+// syntax errors.
+func errorPositions(fset *token.FileSet, err error) []token.Position {
+	switch err := err.(type) {
+	case types.Error:
+		return []token.Position{fset.Position(err.Pos)}
+	case *scanner.Error:
+		return []token.Position{err.Pos}
+	case scanner.ErrorList:
+		var positions []token.Position
+		for _, e := range err {
+			positions = append(positions, e.Pos)
+		}
+		return positions
+	}
+	return nil
+}
+
+// inFile reports whether pos is in the file constants are taken from.
+func (pkg *Package) inFile(pos token.Pos) bool {
+	return len(pkg.file) == 0 || filepath.Base(pkg.fset.Position(pos).Filename) == pkg.file
+}
+
+// Files returns the paths of the files the package is parsed from, in the
+// order constants are collected.
+func (pkg *Package) Files() []string {
+	var files []string
+	for _, file := range pkg.files {
+		files = append(files, pkg.fset.File(file.Pos()).Name())
+	}
+	return files
+}
+
+// HasType reports whether the package declares the named type.
+func (pkg *Package) HasType(typeName string) bool {
+	_, ok := pkg.scope.Lookup(typeName).(*types.TypeName)
+	return ok
+}
+
+// HasName reports whether the package declares name at package level, so
+// files of the package can't import a package under that name.
+func (pkg *Package) HasName(name string) bool {
+	return pkg.scope.Lookup(name) != nil
+}
+
+// ImportName returns the name the package of the import path is imported
+// under by the files of the package, the one of the first file if they differ,
+// and whether any file imports it. Blank and dot imports are disregarded.
+func (pkg *Package) ImportName(path string) (string, bool) {
+	for _, file := range pkg.files {
+		for _, spec := range file.Imports {
+			if p, err := strconv.Unquote(spec.Path.Value); err != nil || p != path {
+				continue
+			}
+			if spec.Name == nil {
+				// The name is the one the imported package declares.
+				if obj, ok := pkg.implicits[spec].(*types.PkgName); ok {
+					return obj.Name(), true
+				}
+			} else if spec.Name.Name != "_" && spec.Name.Name != "." {
+				return spec.Name.Name, true
+			}
+		}
+	}
+	return "", false
+}
+
+// HasOwnMethod reports whether values of the named type have the named
+// method declared in a file not generated by yamlenums, such as a String
+// method written by hand.
+func (pkg *Package) HasOwnMethod(typeName, method string) bool {
+	obj, ok := pkg.scope.Lookup(typeName).(*types.TypeName)
+	if !ok {
+		return false
+	}
+	sel := types.NewMethodSet(obj.Type()).Lookup(obj.Pkg(), method)
+	if sel == nil {
+		return false
+	}
+	name := pkg.fset.Position(sel.Obj().Pos()).Filename
+	for _, file := range pkg.files {
+		if pkg.fset.Position(file.Pos()).Filename != name {
+			continue
+		}
+		for _, group := range file.Comments {
+			if group.Pos() > file.Package {
+				break
+			}
+			for _, c := range group.List {
+				if strings.HasPrefix(c.Text, "// Code generated by yamlenums") && strings.HasSuffix(c.Text, "DO NOT EDIT.") {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// BasicType returns the underlying type of the named type, such as int8 or
+// string. Alias types are rejected, as methods can't be declared for them
+// unless they denote a type of the package, which should be named instead.
+func (pkg *Package) BasicType(typeName string) (*types.Basic, error) {
+	obj, ok := pkg.scope.Lookup(typeName).(*types.TypeName)
+	if !ok {
+		return nil, fmt.Errorf("type %s not found in package %s", typeName, pkg.Name)
+	}
+	if obj.IsAlias() {
+		qualifier := func(other *types.Package) string {
+			if other.Scope() == pkg.scope {
+				return ""
+			}
+			return other.Name()
+		}
+		return nil, fmt.Errorf("cannot generate methods for alias type %s of %s",
+			typeName, types.TypeString(unalias(obj.Type()), qualifier))
+	}
+	basic, ok := obj.Type().Underlying().(*types.Basic)
+	if !ok {
+		return nil, fmt.Errorf("type %s is not a basic type", typeName)
+	}
+	return basic, nil
+}
+
+// EnumTypes returns the names of the integer and string types of the package
+// having constants declared, in lexical order. With Config.File set only the
+// constants of that file count.
+func (pkg *Package) EnumTypes() []string {
+	hasConsts := make(map[*types.TypeName]bool)
+	for _, name := range pkg.scope.Names() {
+		c, ok := pkg.scope.Lookup(name).(*types.Const)
+		if !ok || !pkg.inFile(c.Pos()) {
+			continue
+		}
+		if named, ok := unalias(c.Type()).(*types.Named); ok {
+			hasConsts[named.Obj()] = true
+		}
+	}
+	var names []string
+	for _, name := range pkg.scope.Names() {
+		obj, ok := pkg.scope.Lookup(name).(*types.TypeName)
+		if !ok || !hasConsts[obj] {
+			continue
+		}
+		basic, ok := obj.Type().Underlying().(*types.Basic)
+		if ok && basic.Info()&(types.IsInteger|types.IsString) != 0 {
+			names = append(names, name)
+		}
+	}
+	return names
 }
 
-// generate produces the String method for the named type.
-func (pkg *Package) ValuesOfType(typeName string) ([]string, error) {
-	var values, inspectErrs []string
+// ValuesOfType returns the constants declared with the named type in the order
+// they appear in the source, the files being ordered by name.
+func (pkg *Package) ValuesOfType(typeName string) ([]Value, error) {
+	var values []Value
+	var inspectErrs []string
 	for _, file := range pkg.files {
+		if !pkg.inFile(file.Pos()) {
+			continue
+		}
 		ast.Inspect(file, func(node ast.Node) bool {
 			decl, ok := node.(*ast.GenDecl)
 			if !ok || decl.Tok != token.CONST {
@@ -75,6 +397,11 @@ func (pkg *Package) ValuesOfType(typeName string) ([]string, error) {
 				inspectErrs = append(inspectErrs, err.Error())
 			} else {
 				values = append(values, vs...)
+				// The values of declarations having errors can't be trusted,
+				// as the errors may even hide constants.
+				if len(vs) > 0 {
+					inspectErrs = append(inspectErrs, pkg.errorsIn(decl)...)
+				}
 			}
 			return false
 		})
@@ -83,45 +410,54 @@ func (pkg *Package) ValuesOfType(typeName string) ([]string, error) {
 		return nil, fmt.Errorf("inspecting code:\n\t%v", strings.Join(inspectErrs, "\n\t"))
 	}
 	if len(values) == 0 {
-		return nil, fmt.Errorf("no values defined for type %s", typeName)
+		if _, ok := pkg.scope.Lookup(typeName).(*types.TypeName); !ok {
+			return nil, fmt.Errorf("type %s not found in package %s", typeName, pkg.Name)
+		}
+		if len(pkg.file) > 0 {
+			return nil, fmt.Errorf("type %s found but has no constants in %s", typeName, pkg.file)
+		}
+		// The constants may be in files excluded by build constraints.
+		return nil, fmt.Errorf("type %s found but has no constants", typeName)
 	}
 	return values, nil
 }
 
-func (pkg *Package) valuesOfTypeIn(typeName string, decl *ast.GenDecl) ([]string, error) {
-	var values []string
+func (pkg *Package) valuesOfTypeIn(typeName string, decl *ast.GenDecl) ([]Value, error) {
+	var values []Value
 
-	// The name of the type of the constants we are declaring.
-	// Can change if this is a multi-element declaration.
-	typ := ""
+	// The type checker knows the type of every constant, whether it's spelled
+	// out as in "X T = 1", carried down from a previous line or implied by the
+	// value as in "Y = X".
+	typ, ok := pkg.scope.Lookup(typeName).(*types.TypeName)
+	if !ok {
+		return nil, nil
+	}
 	// Loop over the elements of the declaration. Each element is a ValueSpec:
 	// a list of names possibly followed by a type, possibly followed by values.
-	// If the type and value are both missing, we carry down the type (and value,
-	// but the "go/types" package takes care of that).
 	for _, spec := range decl.Specs {
 		vspec := spec.(*ast.ValueSpec) // Guaranteed to succeed as this is CONST.
-		if vspec.Type == nil && len(vspec.Values) > 0 {
-			// "X = 1". With no type but a value, the constant is untyped.
-			// Skip this vspec and reset the remembered type.
-			typ = ""
-			continue
+
+		comment := ""
+		if c := vspec.Comment; c != nil && len(c.List) == 1 {
+			comment = strings.TrimSpace(c.Text())
 		}
-		if vspec.Type != nil {
-			// "X T". We have a type. Remember it.
-			ident, ok := vspec.Type.(*ast.Ident)
-			if !ok {
-				continue
-			}
-			typ = ident.Name
+		// The doc comment of a declaration of a single constant without
+		// parentheses belongs to the declaration.
+		docGroup := vspec.Doc
+		if docGroup == nil && !decl.Lparen.IsValid() {
+			docGroup = decl.Doc
 		}
-		if typ != typeName {
-			// This is not the type we're looking for.
-			continue
+		doc := ""
+		if docGroup != nil {
+			doc = strings.TrimSpace(docGroup.Text())
+		}
+		override, err := findOverride(vspec)
+		if err != nil {
+			return nil, err
 		}
 
-		// We now have a list of names (from one line of source code) all being
-		// declared with the desired type.
-		// Grab their names and actual values and store them in f.values.
+		// Grab the names and actual values of the constants declared with the
+		// desired type on this line of source code.
 		for _, name := range vspec.Names {
 			if name.Name == "_" {
 				continue
@@ -133,16 +469,63 @@ func (pkg *Package) valuesOfTypeIn(typeName string, decl *ast.GenDecl) ([]string
 			if !ok {
 				return nil, fmt.Errorf("no value for constant %s", name)
 			}
+			if named, ok := unalias(obj.Type()).(*types.Named); !ok || named.Obj() != typ {
+				// This is not the type we're looking for.
+				continue
+			}
 			info := obj.Type().Underlying().(*types.Basic).Info()
-			if info&types.IsInteger == 0 {
-				return nil, fmt.Errorf("can't handle non-integer constant type %s", typ)
+			if info&(types.IsInteger|types.IsString) == 0 {
+				return nil, fmt.Errorf("can't handle non-integer non-string constant type %s", typeName)
 			}
+			// The type checker has evaluated the expression already, be it
+			// built of iota, arithmetic, conversions or other constants.
 			value := obj.(*types.Const).Val() // Guaranteed to succeed as this is CONST.
-			if value.Kind() != constant.Int {
-				log.Fatalf("can't happen: constant is not an integer %s", name)
+			if value.Kind() != constant.Int && value.Kind() != constant.String {
+				return nil, fmt.Errorf("can't evaluate the value of constant %s at %s", name, pkg.fset.Position(name.Pos()))
 			}
-			values = append(values, name.Name)
+			values = append(values, Value{
+				Name:     name.Name,
+				Value:    value,
+				Comment:  comment,
+				Doc:      doc,
+				Override: override,
+			})
 		}
 	}
 	return values, nil
 }
+
+// unalias returns the type t denotes if it's an alias type, as newer type
+// checkers represent aliases with types of their own having the aliased type
+// on the right-hand side.
+func unalias(t types.Type) types.Type {
+	for {
+		alias, ok := t.(interface{ Rhs() types.Type })
+		if !ok {
+			return t
+		}
+		t = alias.Rhs()
+	}
+}
+
+// findOverride looks for a yamlenums:"string" comment among the doc and line
+// comments of vspec and returns the string.
+func findOverride(vspec *ast.ValueSpec) (string, error) {
+	for _, group := range []*ast.CommentGroup{vspec.Doc, vspec.Comment} {
+		if group == nil {
+			continue
+		}
+		for _, c := range group.List {
+			text := strings.TrimSpace(strings.TrimPrefix(c.Text, "//"))
+			if !strings.HasPrefix(text, overridePrefix) {
+				continue
+			}
+			str, err := strconv.Unquote(text[len(overridePrefix):])
+			if err != nil {
+				return "", fmt.Errorf("malformed comment %s of %s", c.Text, vspec.Names[0])
+			}
+			return str, nil
+		}
+	}
+	return "", nil
+}
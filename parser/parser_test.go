@@ -16,9 +16,13 @@ package parser
 
 import (
 	"go/build"
+	"go/constant"
+	"go/types"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -51,3 +55,636 @@ func TestParseFromMultipleGopath(t *testing.T) {
 		t.Fatalf("Parse package (%v): %v", dir, err)
 	}
 }
+
+// writePackage creates a package in a temporary directory holding the given
+// files, whose names may have directories holding other packages of the
+// module foo, and returns the directory.
+func writePackage(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "parser")
+	must(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	must(t, ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte("module foo\n"), 0644))
+	for name, src := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		must(t, os.MkdirAll(filepath.Dir(path), 0755))
+		must(t, ioutil.WriteFile(path, []byte(src), 0644))
+	}
+	return dir
+}
+
+func TestBasicType(t *testing.T) {
+	dir := writePackage(t, map[string]string{"small.go": `
+package foo
+
+type Small int8
+
+const (
+	Negative Small = -1
+	Zero     Small = 0
+)
+`})
+	pkg, err := ParsePackage(dir)
+	must(t, err)
+	basic, err := pkg.BasicType("Small")
+	must(t, err)
+	if basic.Kind() != types.Int8 {
+		t.Errorf("Small is %s, want int8", basic)
+	}
+	values, err := pkg.ValuesOfType("Small")
+	must(t, err)
+	if v, ok := constant.Int64Val(values[0].Value); !ok || v != -1 {
+		t.Errorf("Negative is %s, want -1", values[0].Value)
+	}
+	if _, err := pkg.BasicType("Large"); err == nil {
+		t.Error("found type Large")
+	}
+}
+
+func TestTestFiles(t *testing.T) {
+	dir := writePackage(t, map[string]string{
+		"pill.go": `
+package foo
+
+type Pill int
+
+const Aspirin Pill = 1
+`,
+		"pill_test.go": `
+package foo
+
+const Placebo Pill = 0
+`,
+	})
+	for _, tt := range []struct {
+		conf Config
+		want int
+	}{
+		{Config{}, 1},
+		{Config{Tests: true}, 2},
+	} {
+		pkg, err := tt.conf.ParsePackage(dir)
+		must(t, err)
+		values, err := pkg.ValuesOfType("Pill")
+		must(t, err)
+		if len(values) != tt.want {
+			t.Errorf("%+v parses %d values, want %d", tt.conf, len(values), tt.want)
+		}
+	}
+}
+
+func TestTags(t *testing.T) {
+	dir := writePackage(t, map[string]string{
+		"pill.go": `
+package foo
+
+type Pill int
+`,
+		"pill_foo.go": `//go:build foo
+// +build foo
+
+package foo
+
+const Aspirin Pill = 1
+`,
+		"pill_nofoo.go": `//go:build !foo
+// +build !foo
+
+package foo
+
+const (
+	Placebo Pill = 0
+	Ibuprofen Pill = 2
+)
+`,
+	})
+	for _, tt := range []struct {
+		conf Config
+		want string
+	}{
+		{Config{}, "Placebo"},
+		{Config{Tags: []string{"foo"}}, "Aspirin"},
+	} {
+		pkg, err := tt.conf.ParsePackage(dir)
+		must(t, err)
+		values, err := pkg.ValuesOfType("Pill")
+		must(t, err)
+		if values[0].Name != tt.want {
+			t.Errorf("%+v parses %s first, want %s", tt.conf, values[0].Name, tt.want)
+		}
+	}
+}
+
+func TestEnumTypes(t *testing.T) {
+	dir := writePackage(t, map[string]string{"enums.go": `
+package foo
+
+type Pill int
+
+const Aspirin Pill = 1
+
+type Env string
+
+const Prod Env = "production"
+
+type Empty int
+
+type Ratio float64
+
+const Half Ratio = 0.5
+
+type Point struct{ X, Y int }
+`})
+	pkg, err := ParsePackage(dir)
+	must(t, err)
+	want := []string{"Env", "Pill"}
+	if got := pkg.EnumTypes(); !reflect.DeepEqual(got, want) {
+		t.Errorf("EnumTypes() = %v, want %v", got, want)
+	}
+}
+
+func TestFile(t *testing.T) {
+	dir := writePackage(t, map[string]string{
+		"pill.go": `
+package foo
+
+type Pill int
+
+const Aspirin Pill = 1
+`,
+		"expired.go": `
+package foo
+
+const Expired Pill = 2
+
+type Color int
+
+const Red Color = 0
+`,
+	})
+	pkg, err := (&Config{File: "pill.go"}).ParsePackage(dir)
+	must(t, err)
+	values, err := pkg.ValuesOfType("Pill")
+	must(t, err)
+	if len(values) != 1 || values[0].Name != "Aspirin" {
+		t.Errorf("values of pill.go are %v, want Aspirin only", values)
+	}
+	if got := pkg.EnumTypes(); !reflect.DeepEqual(got, []string{"Pill"}) {
+		t.Errorf("EnumTypes() = %v, want [Pill]", got)
+	}
+	if _, err := (&Config{File: "missing.go"}).ParsePackage(dir); err == nil {
+		t.Error("parsed the package for a missing file")
+	}
+}
+
+func TestParseFiles(t *testing.T) {
+	dir := writePackage(t, map[string]string{
+		"pill.go": `
+package foo
+
+type Pill int
+
+const Aspirin Pill = 1
+`,
+		"pill_test.go": `
+package foo
+
+const Placebo Pill = 0
+`,
+		"color.go": `
+package foo
+
+type Color int
+
+const Red Color = 0
+`,
+	})
+	pkg, err := ParseFiles([]string{filepath.Join(dir, "pill.go"), filepath.Join(dir, "pill_test.go")})
+	must(t, err)
+	values, err := pkg.ValuesOfType("Pill")
+	must(t, err)
+	if len(values) != 2 {
+		t.Errorf("got %d values of Pill, want 2", len(values))
+	}
+	if got := pkg.EnumTypes(); !reflect.DeepEqual(got, []string{"Pill"}) {
+		t.Errorf("EnumTypes() = %v, want [Pill]", got)
+	}
+	if _, err := ParseFiles(nil); err == nil {
+		t.Error("parsed no files")
+	}
+}
+
+func TestAliasValues(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": `
+package foo
+
+type Pill int
+
+const (
+	Paracetamol Pill = iota
+	Acetaminophen = Paracetamol
+	APAP
+	Untyped = 1
+)
+`})
+	pkg, err := ParsePackage(dir)
+	must(t, err)
+	values, err := pkg.ValuesOfType("Pill")
+	must(t, err)
+	var names []string
+	for _, v := range values {
+		names = append(names, v.Name)
+	}
+	if want := []string{"Paracetamol", "Acetaminophen", "APAP"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("constants of Pill are %v, want %v", names, want)
+	}
+}
+
+func TestValuesAcrossFiles(t *testing.T) {
+	dir := writePackage(t, map[string]string{
+		"pill.go": `
+package foo
+
+type Pill int
+
+const (
+	Placebo Pill = iota
+	Aspirin
+)
+`,
+		"pill_extra.go": `
+package foo
+
+const Ibuprofen Pill = 2
+
+const Paracetamol Pill = 3
+`,
+		"a_pill.go": `
+package foo
+
+const Expired Pill = -1
+`,
+	})
+	want := []string{"Expired", "Placebo", "Aspirin", "Ibuprofen", "Paracetamol"}
+	for _, parse := range []func() (*Package, error){
+		func() (*Package, error) { return ParsePackage(dir) },
+		func() (*Package, error) {
+			return ParseFiles([]string{
+				filepath.Join(dir, "pill_extra.go"),
+				filepath.Join(dir, "pill.go"),
+				filepath.Join(dir, "a_pill.go"),
+			})
+		},
+	} {
+		pkg, err := parse()
+		must(t, err)
+		values, err := pkg.ValuesOfType("Pill")
+		must(t, err)
+		var names []string
+		for _, v := range values {
+			names = append(names, v.Name)
+		}
+		if !reflect.DeepEqual(names, want) {
+			t.Errorf("constants of Pill are %v, want %v", names, want)
+		}
+	}
+}
+
+func TestConstantExpressions(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": `
+package foo
+
+type Pill int
+
+const base = 10
+
+const (
+	Placebo Pill = Pill(iota + 100)
+	Aspirin
+	Ibuprofen Pill = Pill(base) * 3
+	Paracetamol = Ibuprofen + Pill(len("abc"))
+	Expired = -Placebo
+)
+`})
+	pkg, err := ParsePackage(dir)
+	must(t, err)
+	values, err := pkg.ValuesOfType("Pill")
+	must(t, err)
+	var got []string
+	for _, v := range values {
+		got = append(got, v.Name+"="+v.Value.String())
+	}
+	want := []string{"Placebo=100", "Aspirin=101", "Ibuprofen=30", "Paracetamol=33", "Expired=-100"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("constants of Pill are %v, want %v", got, want)
+	}
+}
+
+func TestImplicitType(t *testing.T) {
+	dir := writePackage(t, map[string]string{"foo.go": `
+package foo
+
+type Foo int
+
+const (
+	A Foo = iota
+	B
+	_
+	C
+)
+`})
+	pkg, err := ParsePackage(dir)
+	must(t, err)
+	values, err := pkg.ValuesOfType("Foo")
+	must(t, err)
+	var got []string
+	for _, v := range values {
+		got = append(got, v.Name+"="+v.Value.String())
+	}
+	if want := []string{"A=0", "B=1", "C=3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("constants of Foo are %v, want %v", got, want)
+	}
+}
+
+func TestDoc(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": `
+package foo
+
+type Pill int
+
+// Placebo does nothing.
+const Placebo Pill = 0
+
+// The pills.
+const (
+	// Aspirin is
+	// acetylsalicylic acid.
+	Aspirin Pill = iota + 1
+	Ibuprofen // Not this one.
+)
+`})
+	pkg, err := ParsePackage(dir)
+	must(t, err)
+	values, err := pkg.ValuesOfType("Pill")
+	must(t, err)
+	var got []string
+	for _, v := range values {
+		got = append(got, v.Doc)
+	}
+	if want := []string{"Placebo does nothing.", "Aspirin is\nacetylsalicylic acid.", ""}; !reflect.DeepEqual(got, want) {
+		t.Errorf("docs of Pill are %q, want %q", got, want)
+	}
+}
+
+func TestMixedTypes(t *testing.T) {
+	dir := writePackage(t, map[string]string{"enums.go": `
+package foo
+
+type Foo int
+
+type Bar int
+
+const (
+	A Foo = iota
+	B
+	X Bar = iota
+	Y
+	C Foo = 7
+	Z
+)
+`})
+	pkg, err := ParsePackage(dir)
+	must(t, err)
+	// iota counts the lines of the whole block, so it doesn't restart when
+	// the type changes: X is 2. B and Y repeat the lines above them, type
+	// included, and so does Z, being 7 too.
+	for typeName, want := range map[string][]string{
+		"Foo": {"A=0", "B=1", "C=7", "Z=7"},
+		"Bar": {"X=2", "Y=3"},
+	} {
+		values, err := pkg.ValuesOfType(typeName)
+		must(t, err)
+		var got []string
+		for _, v := range values {
+			got = append(got, v.Name+"="+v.Value.String())
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("constants of %s are %v, want %v", typeName, got, want)
+		}
+	}
+}
+
+func TestImportedConstants(t *testing.T) {
+	dir := writePackage(t, map[string]string{
+		"pill.go": `
+package foo
+
+import "foo/internal/base"
+
+type Pill int
+
+const (
+	Placebo Pill = base.Base + iota
+	Aspirin
+	Ibuprofen = Pill(base.Offset) * 2
+)
+`,
+		"internal/base/base.go": `
+package base
+
+const Base = 100
+
+const Offset = Base + 5
+`,
+	})
+	pkg, err := ParsePackage(dir)
+	must(t, err)
+	values, err := pkg.ValuesOfType("Pill")
+	must(t, err)
+	var got []string
+	for _, v := range values {
+		got = append(got, v.Name+"="+v.Value.String())
+	}
+	if want := []string{"Placebo=100", "Aspirin=101", "Ibuprofen=210"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("constants of Pill are %v, want %v", got, want)
+	}
+}
+
+func TestConstantExpressionError(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": `
+package foo
+
+type Pill int
+
+const Placebo Pill = Pill(missing + 1)
+`})
+	_, err := ParsePackage(dir)
+	if err == nil || !strings.Contains(err.Error(), "pill.go:6:27: undefined: missing") {
+		t.Errorf("parsing fails with %v", err)
+	}
+}
+
+func TestAliasType(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": `
+package foo
+
+type Level int
+
+type Lvl = Level
+
+type Pill = int
+
+const (
+	Low Lvl = iota
+	High
+)
+
+const Placebo Pill = 0
+`})
+	pkg, err := ParsePackage(dir)
+	must(t, err)
+	for typeName, want := range map[string]string{
+		"Lvl":  "cannot generate methods for alias type Lvl of Level",
+		"Pill": "cannot generate methods for alias type Pill of int",
+	} {
+		if _, err := pkg.BasicType(typeName); err == nil || err.Error() != want {
+			t.Errorf("BasicType(%q) fails with %v, want %s", typeName, err, want)
+		}
+	}
+	if _, err := pkg.BasicType("Level"); err != nil {
+		t.Errorf("BasicType(\"Level\") fails with %v", err)
+	}
+	if got := pkg.EnumTypes(); !reflect.DeepEqual(got, []string{"Level"}) {
+		t.Errorf("EnumTypes() = %v, want [Level]", got)
+	}
+	values, err := pkg.ValuesOfType("Level")
+	must(t, err)
+	if len(values) != 2 || values[0].Name != "Low" || values[1].Name != "High" {
+		t.Errorf("constants of Level declared as Lvl are %v", values)
+	}
+}
+
+func TestMissingValues(t *testing.T) {
+	dir := writePackage(t, map[string]string{
+		"pill.go": "package foo\n\ntype Pill int\n",
+		"pill_special.go": `// +build special
+
+package foo
+
+const Placebo Pill = 0
+`,
+	})
+	pkg, err := ParsePackage(dir)
+	must(t, err)
+	for typeName, want := range map[string]string{
+		"Pill": "type Pill found but has no constants",
+		"Pil":  "type Pil not found in package foo",
+	} {
+		if _, err := pkg.ValuesOfType(typeName); err == nil || err.Error() != want {
+			t.Errorf("ValuesOfType(%q) fails with %v, want %s", typeName, err, want)
+		}
+	}
+	if _, err := pkg.BasicType("Pil"); err == nil || err.Error() != "type Pil not found in package foo" {
+		t.Errorf("BasicType(\"Pil\") fails with %v", err)
+	}
+	if !pkg.HasType("Pill") || pkg.HasType("Pil") {
+		t.Error("HasType misjudges Pill and Pil")
+	}
+}
+
+func TestBestEffort(t *testing.T) {
+	dir := writePackage(t, map[string]string{
+		"pill.go": `
+package foo
+
+type Pill int
+
+const (
+	Placebo Pill = iota
+	Aspirin
+)
+`,
+		"broken.go": `
+package foo
+
+func broken() int {
+	return "not an int"
+}
+`,
+	})
+	if _, err := ParsePackage(dir); err == nil {
+		t.Fatal("parsing a broken package succeeded")
+	}
+	pkg, err := (&Config{BestEffort: true}).ParsePackage(dir)
+	must(t, err)
+	if len(pkg.Errors()) != 1 {
+		t.Errorf("the errors ignored are %v", pkg.Errors())
+	}
+	values, err := pkg.ValuesOfType("Pill")
+	must(t, err)
+	if len(values) != 2 {
+		t.Errorf("constants of Pill are %v", values)
+	}
+
+	dir = writePackage(t, map[string]string{"pill.go": `
+package foo
+
+type Pill int
+
+const (
+	Placebo Pill = iota
+	Aspirin
+	Ibuprofen = missing
+)
+`})
+	pkg, err = (&Config{BestEffort: true}).ParsePackage(dir)
+	must(t, err)
+	if _, err := pkg.ValuesOfType("Pill"); err == nil || !strings.Contains(err.Error(), "undefined: missing") {
+		t.Errorf("constants of a broken declaration give %v", err)
+	}
+}
+
+func TestImportName(t *testing.T) {
+	dir := writePackage(t, map[string]string{
+		"a.go": `
+package foo
+
+import (
+	_ "image/png"
+	js "encoding/json"
+)
+
+var _ = js.Marshal
+`,
+		"b.go": `
+package foo
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+var _, _ = json.Marshal, strings.ToLower
+
+var yaml int
+`,
+	})
+	pkg, err := ParsePackage(dir)
+	must(t, err)
+	for _, tt := range []struct {
+		path string
+		name string
+		ok   bool
+	}{
+		{"encoding/json", "js", true},
+		{"strings", "strings", true},
+		{"image/png", "", false},
+		{"fmt", "", false},
+	} {
+		if name, ok := pkg.ImportName(tt.path); name != tt.name || ok != tt.ok {
+			t.Errorf("ImportName(%q) = %q, %v, want %q, %v", tt.path, name, ok, tt.name, tt.ok)
+		}
+	}
+	if !pkg.HasName("yaml") || pkg.HasName("json") {
+		t.Errorf("HasName tells yaml %v and json %v", pkg.HasName("yaml"), pkg.HasName("json"))
+	}
+}
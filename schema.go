@@ -0,0 +1,63 @@
+// Copyright 2020 igrmk. All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to writing, software distributed
+// under the License is distributed on a "AS IS" BASIS, WITHOUT WARRANTIES OR
+// CONDITIONS OF ANY KIND, either express or implied.
+//
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"path/filepath"
+	"strings"
+)
+
+// schema is the JSON Schema of a type, listing the strings its constants are
+// marshaled to. The fields are in the order they are written in.
+type schema struct {
+	Title string   `json:"title,omitempty"`
+	Type  string   `json:"type"`
+	Enum  []string `json:"enum,omitempty"`
+	// Items describes the elements when -bitflags marshals to sequences.
+	Items *schema `json:"items,omitempty"`
+}
+
+// schemaFile returns the path of the schema of the named type, which is next
+// to the output file at path, or - if the output is written to the standard
+// output too.
+func schemaFile(path, typeName string) string {
+	if path == "-" {
+		return path
+	}
+	if *fileCase == "snake" {
+		typeName = transforms["snake"](typeName)
+	}
+	return filepath.Join(filepath.Dir(path), strings.ToLower(typeName)+".schema.json")
+}
+
+// generateSchema returns the JSON Schema of the named type. Aliases are
+// listed too, as unmarshaling accepts them.
+func generateSchema(typeName string, values []value) []byte {
+	s := schema{Title: typeName, Type: "string"}
+	for _, v := range values {
+		if !v.Repeat {
+			s.Enum = append(s.Enum, v.Str)
+		}
+	}
+	if *bitFlags {
+		s = schema{Title: typeName, Type: "array", Items: &schema{Type: "string", Enum: s.Enum}}
+	}
+	src, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		log.Fatalf("generating schema: %s", err)
+	}
+	return append(src, '\n')
+}
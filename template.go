@@ -16,68 +16,1106 @@
 
 package main
 
-import "text/template"
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
 
-var generatedTmpl = template.Must(template.New("generated").Parse(`
-// generated by yamlenums {{.Command}}; DO NOT EDIT
+// maxListed is how many valid strings errors list at most.
+const maxListed = 20
 
-package {{.PackageName}}
+// funcs are the functions available to the templates besides the predefined
+// ones.
+var funcs = template.FuncMap{
+	// strs returns the strings of the values, listing the ones of aliases
+	// marshaled to the same strings once.
+	"strs": func(values []value) []string {
+		var strs []string
+		for _, v := range values {
+			if !v.Repeat {
+				strs = append(strs, v.Str)
+			}
+		}
+		return strs
+	},
+	"join": strings.Join,
+	// list returns the strings of the values in brackets, as errors list
+	// them, eliding the ones beyond maxListed.
+	"list": func(values []value) string {
+		var strs []string
+		for _, v := range values {
+			if !v.Repeat {
+				strs = append(strs, v.Str)
+			}
+		}
+		if len(strs) > maxListed {
+			strs = append(strs[:maxListed], fmt.Sprintf("and %d more", len(strs)-maxListed))
+		}
+		return "[" + strings.Join(strs, " ") + "]"
+	},
+	// distinct returns the first n values that aren't aliases, or fewer if
+	// there aren't as many.
+	"distinct": func(values []value, n int) []value {
+		var result []value
+		for _, v := range values {
+			if !v.Alias && len(result) < n {
+				result = append(result, v)
+			}
+		}
+		return result
+	},
+	// yamlLines returns the lines gopkg.in/yaml.v3 marshals the string s
+	// to, tagged with tag unless it's empty, as MarshalYAML does.
+	"yamlLines": func(s, tag string) ([]string, error) {
+		var v interface{} = s
+		if len(tag) > 0 {
+			v = &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: s}
+		}
+		b, err := yaml.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		return strings.Split(strings.TrimSuffix(string(b), "\n"), "\n"), nil
+	},
+	// comment turns text into line comments, one per line, for the doc
+	// comments of the constants to appear in the code generated.
+	"comment": func(text string) string {
+		var b strings.Builder
+		for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+			b.WriteString(strings.TrimRight("// "+line, " ") + "\n")
+		}
+		return b.String()
+	},
+	// unknown returns a string naming none of the values, even ignoring
+	// case.
+	"unknown": func(typeName string, values []value) string {
+		s := "Unknown" + typeName
+		for i := 0; i < len(values); i++ {
+			if !strings.EqualFold(s, values[i].Str) {
+				continue
+			}
+			s += "_"
+			i = -1
+		}
+		return s
+	},
+}
+
+// header starts the generated files, the license header coming first and
+// build constraints next as required. Blank lines separate them, so neither
+// makes the package doc.
+const header = `
+{{- if .License}}{{.License}}
+{{end}}
+{{- if .BuildTag}}
+//go:build {{.BuildTag}}
+// +build {{.BuildTag}}
+{{end}}
+// Code generated by yamlenums{{with .Command}} {{.}}{{end}}; DO NOT EDIT.
+`
 
+var generatedTmpl = template.Must(template.New("generated").Funcs(funcs).Parse(header + `
+package {{.PackageName}}
+{{- /* sigs.k8s.io/yaml unmarshals YAML by converting it to JSON, so the JSON
+    methods serve it. */}}
+{{- $sigs := and .YAML (eq .YAMLPackage "sigs")}}
+{{- /* Both go-yaml versions accept the v2 form of UnmarshalYAML, so both
+    gets the v2 methods. */}}
+{{- $v2 := or (eq .YAMLPackage "v2") (eq .YAMLPackage "both")}}
+{{- /* The strings of the constants may come from comments holding quotes,
+    backslashes and the like, so they always go through printf "%q". */}}
 import (
+{{- if .SQL}}
+    "database/sql"
+    "database/sql/driver"
+{{- end}}
+{{- if .Text}}
+    "encoding"
+{{- end}}
+{{- if or .JSON $sigs}}
+    "encoding/json"
+{{- end}}
+{{- if .XML}}
+    "encoding/xml"
+{{- end}}
+{{- if .FlagValue}}
+    "flag"
+{{- end}}
     "fmt"
+{{- if eq .Lookup "binary"}}
+    "sort"
+{{- end}}
+{{- if or .IgnoreCase (and .YAML .BitFlags .FlagString)}}
+    "strings"
+{{- end}}
+{{- /* Only the v3 methods refer to the yaml package, UnmarshalYAML taking a
+    *yaml.Node. The v2 ones are checked against their method sets, so v2 isn't
+    imported just for that. */}}
+{{- if and .YAML (eq .YAMLPackage "v3")}}
 
-    "gopkg.in/yaml.v3"
+    {{if ne .YAMLName "yaml"}}{{.YAMLName}} {{end}}"gopkg.in/yaml.v3"
+{{- end}}
 )
 
 {{range $typename, $values := .TypesAndValues}}
+{{- $parse := printf "Parse%s" $typename}}
+{{- if not $.Parse}}{{$parse = printf "_parse%s" $typename}}{{end}}
+{{- $type := index $.Types $typename}}
+{{- $verb := "%d"}}{{$value := "r"}}
+{{- if $type.String}}{{$verb = "%q"}}{{$value = "string(r)"}}{{end}}
+{{- $int := "int64"}}{{if $type.Unsigned}}{{$int = "uint64"}}{{end}}
+{{- $decode := "value.Decode"}}{{if $v2}}{{$decode = "unmarshal"}}{{end}}
+{{- $at := " at line %d, column %d"}}{{$atArgs := ", value.Line, value.Column"}}
+{{- if $v2}}{{$at = ""}}{{$atArgs = ""}}{{end}}
+{{- /* For v3 the errors for strings naming no constant tell the position of
+    the node right after the string, so they are made here rather than taken
+    from the parse function. */}}
+{{- $invalid := "err"}}
+{{- if not $v2}}
+{{- if $.ErrType}}{{$invalid = printf "&Invalid%sError{Value: s, Line: value.Line, Column: value.Column}" $typename}}
+{{- else}}{{$invalid = printf "fmt.Errorf(%q, s%s, %q)" (printf "invalid %s %%q%s: valid values are %%s" $typename $at) $atArgs (list $values)}}
+{{- end}}
+{{- end}}
+{{- $fallback := index $.Fallbacks $typename}}
+{{- $nameToValue := printf "_%sNameToValue" $typename}}{{$valueToName := printf "_%sValueToName" $typename}}
+{{- if $.ExportMaps}}{{$nameToValue = printf "%sNameToValue" $typename}}{{$valueToName = printf "%sValueToName" $typename}}{{end}}
+{{- /* With -overridable the string of r is looked up by _TName, which consults
+    TMarshalMap and String methods first. */}}
+{{- $name := printf "%s[r]" $valueToName}}{{if $.Overridable}}{{$name = printf "_%sName(r)" $typename}}{{end}}
+{{- /* MarshalYAML has a pointer receiver with -ptrmarshal and dereferences it
+    into r, so its bodies are the same either way. */}}
+{{- $marshaler := $typename}}{{$recv := printf "r %s" $typename}}
+{{- if $.PtrMarshal}}{{$marshaler = printf "*%s" $typename}}{{$recv = printf "p *%s" $typename}}{{end}}
 
 var (
-    _{{$typename}}NameToValue = map[string]{{$typename}} {
-        {{range $values}}"{{.}}": {{.}},
+{{- if or (eq $.Lookup "map") $.ExportMaps}}
+{{- if $.ExportMaps}}
+    // {{$nameToValue}} maps every string a {{$typename}} constant is marshaled to,
+    // aliases included, to its value. The generated methods share the map, so
+    // it must not be modified.
+{{- end}}
+    {{$nameToValue}} = map[string]{{$typename}} {
+        {{range $values}}{{if not .Repeat}}{{printf "%q" .Str}}: {{.Name}},
+        {{end}}{{end}}
+    }
+{{- end}}
+{{if eq $.Lookup "binary"}}
+    _{{$typename}}SortedNames = [...]string {
+        {{range $type.Sorted}}{{printf "%q" .Str}},
         {{end}}
     }
 
-    _{{$typename}}ValueToName = map[{{$typename}}]string {
-        {{range $values}}{{.}}: "{{.}}",
+    _{{$typename}}SortedValues = [...]{{$typename}} {
+        {{range $type.Sorted}}{{.Name}},
         {{end}}
     }
+{{- end}}
+{{if $.ExportMaps}}
+    // {{$valueToName}} maps every {{$typename}} value to the string it is marshaled
+    // to, the one of the first constant declared for aliases. The generated
+    // methods share the map, so it must not be modified.
+{{- end}}
+    {{$valueToName}} = map[{{$typename}}]string {
+        {{range $values}}{{if not .Alias}}{{.Name}}: {{printf "%q" .Str}},
+        {{end}}{{end}}
+    }
+{{- if $.Overridable}}
+
+    // {{$typename}}MarshalMap, if set, maps {{$typename}} values to the strings they are
+    // marshaled to instead of the generated ones. Values missing from it are
+    // marshaled as usual. It is read without locking, so it must be set before
+    // use, such as in init, and not modified afterwards.
+    {{$typename}}MarshalMap map[{{$typename}}]string
+
+    // {{$typename}}UnmarshalMap, if set, maps strings to the {{$typename}} values they are
+    // unmarshaled to, consulted before the generated strings. It is read
+    // without locking, so it must be set before use, such as in init, and not
+    // modified afterwards.
+    {{$typename}}UnmarshalMap map[string]{{$typename}}
+{{- end}}
 )
 
+{{- if $.Overridable}}
+
+// _{{$typename}}Name returns the string r is marshaled to, looking it up in
+// {{$typename}}MarshalMap first.
+func _{{$typename}}Name(r {{$typename}}) (string, bool) {
+    if s, ok := {{$typename}}MarshalMap[r]; ok {
+        return s, true
+    }
+{{- if not $.Stringer}}
+    if s, ok := interface{}(r).(fmt.Stringer); ok {
+        return s.String(), true
+    }
+{{- end}}
+    s, ok := {{$valueToName}}[r]
+    return s, ok
+}
+{{- end}}
+
+{{- $zero := "0"}}{{if $type.String}}{{$zero = "\"\""}}{{end}}
+{{- if or $.Stringer $.YAML $.JSON $.Text $.XML $.SQL $.FlagValue}}
+// The interfaces {{$typename}} satisfies with the generated methods, checked at compile time.
+var (
+{{- if $.Stringer}}
+    _ fmt.Stringer = {{$typename}}({{$zero}})
+{{- end}}
+{{- if and $.YAML (eq $.YAMLPackage "v3")}}
+{{- if $.PtrMarshal}}
+    _ {{$.YAMLName}}.Marshaler = (*{{$typename}})(nil)
+{{- else}}
+    _ {{$.YAMLName}}.Marshaler = {{$typename}}({{$zero}})
+{{- end}}
+    _ {{$.YAMLName}}.Unmarshaler = (*{{$typename}})(nil)
+{{- else if and $.YAML $v2}}
+{{- if $.PtrMarshal}}
+    _ interface{ MarshalYAML() (interface{}, error) } = (*{{$typename}})(nil)
+{{- else}}
+    _ interface{ MarshalYAML() (interface{}, error) } = {{$typename}}({{$zero}})
+{{- end}}
+    _ interface{ UnmarshalYAML(func(interface{}) error) error } = (*{{$typename}})(nil)
+{{- end}}
+{{- if or $.JSON $sigs}}
+    _ json.Marshaler = {{$typename}}({{$zero}})
+    _ json.Unmarshaler = (*{{$typename}})(nil)
+{{- end}}
+{{- if $.Text}}
+    _ encoding.TextMarshaler = {{$typename}}({{$zero}})
+    _ encoding.TextUnmarshaler = (*{{$typename}})(nil)
+{{- end}}
+{{- if $.XML}}
+    _ xml.Marshaler = {{$typename}}({{$zero}})
+    _ xml.Unmarshaler = (*{{$typename}})(nil)
+{{- end}}
+{{- if $.SQL}}
+    _ driver.Valuer = {{$typename}}({{$zero}})
+    _ sql.Scanner = (*{{$typename}})(nil)
+{{- end}}
+{{- if $.FlagValue}}
+    _ flag.Value = (*{{$typename}})(nil)
+{{- end}}
+)
+{{- end}}
+
+{{if $.Stringer}}
+// String is generated so {{$typename}} satisfies fmt.Stringer.
+func (r {{$typename}}) String() string {
+    s, ok := {{$name}}
+    if !ok {
+        return fmt.Sprintf("{{$typename}}({{$verb}})", {{$value}})
+    }
+    return s
+}
+{{else if or (eq $.Lookup "map") $.ExportMaps}}
 func init() {
     var v {{$typename}}
     if _, ok := interface{}(v).(fmt.Stringer); ok {
-        _{{$typename}}NameToValue = map[string]{{$typename}} {
-            {{range $values}}interface{}({{.}}).(fmt.Stringer).String(): {{.}},
+        {{$nameToValue}} = map[string]{{$typename}} {
+            {{range $values}}interface{}({{.Name}}).(fmt.Stringer).String(): {{.Name}},
             {{end}}
         }
+{{- if $.ExportMaps}}
+        {{$valueToName}} = map[{{$typename}}]string {
+            {{range $values}}{{if not .Alias}}{{.Name}}: interface{}({{.Name}}).(fmt.Stringer).String(),
+            {{end}}{{end}}
+        }
+{{- end}}
+    }
+}
+{{end}}
+
+{{if $.Valid}}
+// IsValid reports whether r is one of the {{$typename}} constants.
+func (r {{$typename}}) IsValid() bool {
+    _, ok := {{$valueToName}}[r]
+    return ok
+}
+{{end}}
+
+{{if $.Validate}}
+// Validate returns nil if r is one of the {{$typename}} constants and an error
+// otherwise.
+func (r {{$typename}}) Validate() error {
+    if _, ok := {{$valueToName}}[r]; ok {
+        return nil
+    }
+{{- if $.ErrType}}
+{{- if $type.String}}
+    return &Invalid{{$typename}}Error{Value: string(r)}
+{{- else}}
+    return &Invalid{{$typename}}Error{Value: fmt.Sprint({{$int}}(r))}
+{{- end}}
+{{- else}}
+    return fmt.Errorf("invalid {{$typename}}: {{$verb}}", {{$value}})
+{{- end}}
+}
+{{end}}
+
+{{if $.All}}
+// All{{$typename}} returns the {{$typename}} values in the order they are declared.
+func All{{$typename}}() []{{$typename}} {
+    return []{{$typename}}{
+        {{range $values}}{{if not .Alias}}{{if and $.Docs .Doc}}{{comment .Doc}}{{end}}{{.Name}},
+        {{end}}{{end}}
+    }
+}
+{{end}}
+
+{{if $.Names}}
+// {{$typename}}Names returns the strings the {{$typename}} values are marshaled to in
+// the order they are declared.
+func {{$typename}}Names() []string {
+{{- if not $.Stringer}}
+    var v {{$typename}}
+    if _, ok := interface{}(v).(fmt.Stringer); ok {
+        return []string{
+            {{range $values}}{{if not .Alias}}{{if and $.Docs .Doc}}{{comment .Doc}}{{end}}interface{}({{.Name}}).(fmt.Stringer).String(),
+            {{end}}{{end}}
+        }
+    }
+{{- end}}
+    return []string{
+        {{range $values}}{{if not .Alias}}{{if and $.Docs .Doc}}{{comment .Doc}}{{end}}{{printf "%q" .Str}},
+        {{end}}{{end}}
+    }
+}
+{{end}}
+
+{{if $.Ordinal}}
+// _{{$typename}}Ordinals lists the distinct {{$typename}} values in the order they are declared.
+var _{{$typename}}Ordinals = [...]{{$typename}}{
+    {{range $values}}{{if not .Alias}}{{.Name}},
+    {{end}}{{end}}
+}
+
+// Ordinal returns the position of r among the distinct {{$typename}} values in
+// the order they are declared, or -1 if r is none of them.
+func (r {{$typename}}) Ordinal() int {
+    switch r {
+    {{- range $values}}{{if not .Alias}}
+    case {{.Name}}:
+        return {{.Ordinal}}
+    {{- end}}{{end}}
+    }
+    return -1
+}
+
+// {{$typename}}FromOrdinal returns the {{$typename}} value at position i in the
+// order the distinct values are declared.
+func {{$typename}}FromOrdinal(i int) ({{$typename}}, error) {
+    if i < 0 || i >= len(_{{$typename}}Ordinals) {
+        var v {{$typename}}
+        return v, fmt.Errorf("invalid ordinal of {{$typename}}: %d", i)
+    }
+    return _{{$typename}}Ordinals[i], nil
+}
+{{end}}
+
+{{if $.NameFunc}}
+// {{$typename}}Name returns the name of the {{$typename}} constant declared first for v,
+// or "" if v is none of them.
+func {{$typename}}Name(v {{$typename}}) string {
+    switch v {
+    {{- range $values}}{{if not .Alias}}
+    case {{.Name}}:
+        return {{printf "%q" .Name}}
+    {{- end}}{{end}}
+    }
+    return ""
+}
+{{end}}
+
+{{if $.Navigate}}
+// Next returns the {{$typename}} value declared after r. It returns r and false
+// if r is the last value or none of them.
+func (r {{$typename}}) Next() ({{$typename}}, bool) {
+    switch r {
+    {{- $prev := ""}}{{range $values}}{{if not .Alias}}{{if $prev}}
+    case {{$prev}}:
+        return {{.Name}}, true
+    {{- end}}{{$prev = .Name}}{{end}}{{end}}
     }
+    return r, false
 }
 
-// MarshalYAML is generated so {{$typename}} satisfies yaml.Marshaler.
-func (r {{$typename}}) MarshalYAML() ([]byte, error) {
+// Prev returns the {{$typename}} value declared before r. It returns r and false
+// if r is the first value or none of them.
+func (r {{$typename}}) Prev() ({{$typename}}, bool) {
+    switch r {
+    {{- $prev := ""}}{{range $values}}{{if not .Alias}}{{if $prev}}
+    case {{.Name}}:
+        return {{$prev}}, true
+    {{- end}}{{$prev = .Name}}{{end}}{{end}}
+    }
+    return r, false
+}
+{{end}}
+
+{{if and $.YAML (not $sigs) $.BitFlags}}
+// MarshalYAML is generated so {{$marshaler}} satisfies yaml.Marshaler. It
+{{- if $.FlagString}}
+// marshals r to the names of its bits in declaration order joined by {{printf "%q" $.FlagSep}}.
+{{- else}}
+// marshals r to the sequence of the names of its bits in declaration order.
+{{- end}}
+func ({{$recv}}) MarshalYAML() (interface{}, error) {
+{{- if $.PtrMarshal}}
+    if p == nil {
+        return nil, nil
+    }
+    r := *p
+{{- end}}
+    names := []string{}
+    rest := r
+    {{- range $values}}{{if not (or .Alias .Zero)}}
+    if r&{{.Name}} != 0 {
+        names = append(names, {{printf "%q" .Str}})
+        rest &^= {{.Name}}
+    }
+    {{- end}}{{end}}
+    if rest != 0 {
+        return nil, fmt.Errorf("invalid {{$typename}}: %d has bits %d of no constant", r, rest)
+    }
+{{- if $.FlagString}}
+    return strings.Join(names, {{printf "%q" $.FlagSep}}), nil
+{{- else}}
+    return names, nil
+{{- end}}
+}
+
+{{if $.FlagString}}
+// UnmarshalYAML is generated so {{$typename}} satisfies yaml.Unmarshaler. It
+// expects names joined by {{printf "%q" $.FlagSep}} and OR-s their bits. The empty
+// string is zero.
+{{- if $v2}}
+func (r *{{$typename}}) UnmarshalYAML(unmarshal func(interface{}) error) error {
+{{- else}}
+func (r *{{$typename}}) UnmarshalYAML(value *{{$.YAMLName}}.Node) error {
+	if value.Kind != {{$.YAMLName}}.ScalarNode {
+		kind := "non-scalar"
+		switch value.Kind {
+		case {{$.YAMLName}}.MappingNode:
+			kind = "mapping"
+		case {{$.YAMLName}}.SequenceNode:
+			kind = "sequence"
+		}
+		return fmt.Errorf("expected scalar for {{$typename}}, got %s{{$at}}", kind{{$atArgs}})
+	}
+{{- end}}
+{{- if $v2}}
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return fmt.Errorf("{{$typename}} should be a string")
+	}
+{{- else}}
+{{- /* A scalar holds its string as it is unless it's null or binary, so
+    taking it spares the reflection and the allocations of Decode. */}}
+	s := value.Value
+	switch value.ShortTag() {
+	case "!!null":
+		s = ""
+	case "!!binary":
+		if value.Decode(&s) != nil {
+			return fmt.Errorf("{{$typename}} should be a string{{$at}}"{{$atArgs}})
+		}
+	}
+{{- end}}
+	var v {{$typename}}
+	if s != "" {
+		for _, name := range strings.Split(s, {{printf "%q" $.FlagSep}}) {
+			name = strings.TrimSpace(name)
+			bit, err := {{$parse}}(name)
+			if err != nil {
+{{- if $v2}}
+				return err
+{{- else if $.ErrType}}
+				return &Invalid{{$typename}}Error{Value: name, Line: value.Line, Column: value.Column}
+{{- else}}
+				return fmt.Errorf("invalid {{$typename}} %q{{$at}}: valid values are %s", name{{$atArgs}}, {{printf "%q" (list $values)}})
+{{- end}}
+			}
+			v |= bit
+		}
+	}
+	*r = v
+	return nil
+}
+{{else}}
+// UnmarshalYAML is generated so {{$typename}} satisfies yaml.Unmarshaler. It
+// expects a sequence of names and OR-s their bits.
+{{- if $v2}}
+func (r *{{$typename}}) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var names []string
+	if err := unmarshal(&names); err != nil {
+		return fmt.Errorf("{{$typename}} should be a sequence of strings")
+	}
+	var v {{$typename}}
+	for _, s := range names {
+		bit, err := {{$parse}}(s)
+		if err != nil {
+			return err
+		}
+		v |= bit
+	}
+{{- else}}
+func (r *{{$typename}}) UnmarshalYAML(value *{{$.YAMLName}}.Node) error {
+	if value.Kind != {{$.YAMLName}}.SequenceNode {
+		kind := "non-sequence"
+		switch value.Kind {
+		case {{$.YAMLName}}.MappingNode:
+			kind = "mapping"
+		case {{$.YAMLName}}.ScalarNode:
+			kind = "scalar"
+		}
+		return fmt.Errorf("expected sequence for {{$typename}}, got %s{{$at}}", kind{{$atArgs}})
+	}
+	var v {{$typename}}
+	for _, value := range value.Content {
+		if value.Kind != {{$.YAMLName}}.ScalarNode {
+			return fmt.Errorf("{{$typename}} should be a sequence of strings{{$at}}"{{$atArgs}})
+		}
+		s := value.Value
+		switch value.ShortTag() {
+		case "!!null":
+			s = ""
+		case "!!binary":
+			if value.Decode(&s) != nil {
+				return fmt.Errorf("{{$typename}} should be a sequence of strings{{$at}}"{{$atArgs}})
+			}
+		}
+		bit, err := {{$parse}}(s)
+		if err != nil {
+			return {{$invalid}}
+		}
+		v |= bit
+	}
+{{- end}}
+	*r = v
+	return nil
+}
+{{end}}
+{{else if and $.YAML (not $sigs)}}
+// MarshalYAML is generated so {{$marshaler}} satisfies yaml.Marshaler.
+{{- if $.YAMLTag}}
+// It marshals r to a scalar tagged {{$.YAMLTag}}.
+{{- end}}
+{{- if $.MarshalInt}}
+// It marshals r as an integer.
+func ({{$recv}}) MarshalYAML() (interface{}, error) {
+{{- if $.PtrMarshal}}
+    if p == nil {
+        return nil, nil
+    }
+    r := *p
+{{- end}}
+{{- if ne $.MarshalUnknown "int"}}
+    if _, ok := {{$valueToName}}[r]; !ok {
+        return nil, fmt.Errorf("invalid {{$typename}}: %d", r)
+    }
+{{- end}}
+    return {{$int}}(r), nil
+}
+{{- else}}
+func ({{$recv}}) MarshalYAML() (interface{}, error) {
+{{- if $.PtrMarshal}}
+    if p == nil {
+        return nil, nil
+    }
+    r := *p
+{{- end}}
+{{- if not (or $.Stringer $.Overridable)}}
     if s, ok := interface{}(r).(fmt.Stringer); ok {
-        return yaml.Marshal(s.String())
+{{- if $.YAMLTag}}
+        return &{{$.YAMLName}}.Node{Kind: {{$.YAMLName}}.ScalarNode, Tag: {{printf "%q" $.YAMLTag}}, Value: s.String()}, nil
+{{- else}}
+        return s.String(), nil
+{{- end}}
     }
-    s, ok := _{{$typename}}ValueToName[r]
+{{- end}}
+    s, ok := {{$name}}
     if !ok {
-        return nil, fmt.Errorf("invalid {{$typename}}: %d", r)
+{{- if and (eq $.MarshalUnknown "int") (not $type.String)}}
+        return {{$int}}(r), nil
+{{- else}}
+        return nil, fmt.Errorf("invalid {{$typename}}: {{$verb}}", {{$value}})
+{{- end}}
     }
-    return yaml.Marshal(s)
+{{- if $.YAMLTag}}
+    return &{{$.YAMLName}}.Node{Kind: {{$.YAMLName}}.ScalarNode, Tag: {{printf "%q" $.YAMLTag}}, Value: s}, nil
+{{- else}}
+    return s, nil
+{{- end}}
 }
+{{- end}}
 
 // UnmarshalYAML is generated so {{$typename}} satisfies yaml.Unmarshaler.
-func (r *{{$typename}}) UnmarshalYAML(unmarshal func(v interface{}) error) error {
-    var s string
+{{- if $fallback}}
+// Unknown names are unmarshaled as {{$fallback}}.
+{{- end}}
+{{- if $v2}}
+func (r *{{$typename}}) UnmarshalYAML(unmarshal func(interface{}) error) error {
+{{- else}}
+func (r *{{$typename}}) UnmarshalYAML(value *{{$.YAMLName}}.Node) error {
+{{- end}}
+{{- if eq $.YAMLPackage "v3"}}
+	if value.Kind != {{$.YAMLName}}.ScalarNode {
+		kind := "non-scalar"
+		switch value.Kind {
+		case {{$.YAMLName}}.MappingNode:
+			kind = "mapping"
+		case {{$.YAMLName}}.SequenceNode:
+			kind = "sequence"
+		}
+		return fmt.Errorf("expected scalar for {{$typename}}, got %s{{$at}}", kind{{$atArgs}})
+	}
+{{- end}}
+{{- if $v2}}
+	var s string
 	if err := unmarshal(&s); err != nil {
 		return fmt.Errorf("{{$typename}} should be a string")
 	}
-	v, ok := _{{$typename}}NameToValue[s]
-	if !ok {
-		return fmt.Errorf("invalid {{$typename}} %q", s)
+{{- else}}
+{{- /* A scalar holds its string as it is unless it's null or binary, so
+    taking it spares the reflection and the allocations of Decode. */}}
+	s := value.Value
+	switch value.ShortTag() {
+	case "!!null":
+		s = ""
+	case "!!binary":
+		if value.Decode(&s) != nil {
+			return fmt.Errorf("{{$typename}} should be a string{{$at}}"{{$atArgs}})
+		}
+	}
+{{- end}}
+{{- if $.EmptyZero}}
+	if s == "" {
+		// Null decodes to the empty string too.
+		var v {{$typename}}
+		*r = v
+		return nil
+	}
+{{- end}}
+	v, err := {{$parse}}(s)
+	if err != nil {
+{{- if and $.AcceptInt (not $type.String)}}
+		var i {{$int}}
+		if {{$decode}}(&i) != nil {
+{{- if $fallback}}
+			*r = {{$fallback}}
+			return nil
+{{- else}}
+			return {{$invalid}}
+{{- end}}
+		}
+		v = {{$typename}}(i)
+		if _, ok := {{$valueToName}}[v]; !ok || {{$int}}(v) != i {
+{{- if $fallback}}
+			*r = {{$fallback}}
+			return nil
+{{- else if $.ErrType}}
+			return {{$invalid}}
+{{- else}}
+			return fmt.Errorf("invalid {{$typename}}: %d{{$at}}", i{{$atArgs}})
+{{- end}}
+		}
+{{- else if $fallback}}
+		v = {{$fallback}}
+{{- else}}
+		return {{$invalid}}
+{{- end}}
+	}
+	*r = v
+	return nil
+}
+{{end}}
+
+{{if or $.JSON $sigs}}
+// MarshalJSON is generated so {{$typename}} satisfies json.Marshaler.
+{{- if $sigs}}
+// sigs.k8s.io/yaml converts YAML to JSON and back, so this method marshals
+// {{$typename}} to YAML too.
+{{- end}}
+func (r {{$typename}}) MarshalJSON() ([]byte, error) {
+{{- if not (or $.Stringer $.Overridable)}}
+    if s, ok := interface{}(r).(fmt.Stringer); ok {
+        return json.Marshal(s.String())
+    }
+{{- end}}
+    s, ok := {{$name}}
+    if !ok {
+        return nil, fmt.Errorf("invalid {{$typename}}: {{$verb}}", {{$value}})
+    }
+    return json.Marshal(s)
+}
+
+// UnmarshalJSON is generated so {{$typename}} satisfies json.Unmarshaler.
+{{- if $sigs}}
+// sigs.k8s.io/yaml converts YAML to JSON and back, so this method unmarshals
+// {{$typename}} from YAML too.
+{{- end}}
+{{- if $fallback}}
+// Unknown names are unmarshaled as {{$fallback}}.
+{{- end}}
+func (r *{{$typename}}) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("{{$typename}} should be a string, got %s", data)
+	}
+	v, err := {{$parse}}(s)
+	if err != nil {
+{{- if $fallback}}
+		v = {{$fallback}}
+{{- else}}
+		return err
+{{- end}}
 	}
 	*r = v
 	return nil
 }
+{{end}}
+
+{{if $.Text}}
+// MarshalText is generated so {{$typename}} satisfies encoding.TextMarshaler.
+func (r {{$typename}}) MarshalText() ([]byte, error) {
+{{- if not (or $.Stringer $.Overridable)}}
+    if s, ok := interface{}(r).(fmt.Stringer); ok {
+        return []byte(s.String()), nil
+    }
+{{- end}}
+    s, ok := {{$name}}
+    if !ok {
+        return nil, fmt.Errorf("invalid {{$typename}}: {{$verb}}", {{$value}})
+    }
+    return []byte(s), nil
+}
+
+// UnmarshalText is generated so {{$typename}} satisfies encoding.TextUnmarshaler.
+{{- if $fallback}}
+// Unknown names are unmarshaled as {{$fallback}}.
+{{- end}}
+func (r *{{$typename}}) UnmarshalText(text []byte) error {
+	v, err := {{$parse}}(string(text))
+	if err != nil {
+{{- if $fallback}}
+		v = {{$fallback}}
+{{- else}}
+		return err
+{{- end}}
+	}
+	*r = v
+	return nil
+}
+{{end}}
+
+{{if $.XML}}
+// MarshalXML is generated so {{$typename}} satisfies xml.Marshaler. The string of
+// r is the character data of the element.
+func (r {{$typename}}) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+{{- if not (or $.Stringer $.Overridable)}}
+    if s, ok := interface{}(r).(fmt.Stringer); ok {
+        return e.EncodeElement(s.String(), start)
+    }
+{{- end}}
+    s, ok := {{$name}}
+    if !ok {
+        return fmt.Errorf("invalid {{$typename}}: {{$verb}}", {{$value}})
+    }
+    return e.EncodeElement(s, start)
+}
+
+// UnmarshalXML is generated so {{$typename}} satisfies xml.Unmarshaler.
+{{- if $.EmptyZero}}
+// Empty elements are unmarshaled as the zero value.
+{{- end}}
+{{- if $fallback}}
+// Unknown names are unmarshaled as {{$fallback}}.
+{{- end}}
+func (r *{{$typename}}) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var s string
+	if err := d.DecodeElement(&s, &start); err != nil {
+		return err
+	}
+{{- if $.EmptyZero}}
+	if s == "" {
+		var v {{$typename}}
+		*r = v
+		return nil
+	}
+{{- end}}
+	v, err := {{$parse}}(s)
+	if err != nil {
+{{- if $fallback}}
+		v = {{$fallback}}
+{{- else}}
+		return err
+{{- end}}
+	}
+	*r = v
+	return nil
+}
+{{end}}
+
+{{if $.SQL}}
+// Value is generated so {{$typename}} satisfies driver.Valuer.
+func (r {{$typename}}) Value() (driver.Value, error) {
+{{- if not (or $.Stringer $.Overridable)}}
+    if s, ok := interface{}(r).(fmt.Stringer); ok {
+        return s.String(), nil
+    }
+{{- end}}
+    s, ok := {{$name}}
+    if !ok {
+        return nil, fmt.Errorf("invalid {{$typename}}: {{$verb}}", {{$value}})
+    }
+    return s, nil
+}
+
+// Scan is generated so {{$typename}} satisfies sql.Scanner. NULL is scanned
+// as the zero value.
+func (r *{{$typename}}) Scan(src interface{}) error {
+	var s string
+	switch src := src.(type) {
+	case nil:
+		var v {{$typename}}
+		*r = v
+		return nil
+	case string:
+		s = src
+	case []byte:
+		s = string(src)
+	default:
+		return fmt.Errorf("{{$typename}} should be a string, got %T", src)
+	}
+	v, err := {{$parse}}(s)
+	if err != nil {
+		return err
+	}
+	*r = v
+	return nil
+}
+{{end}}
+
+{{if $.FlagValue}}
+// Set is generated so {{$typename}} satisfies flag.Value.
+func (r *{{$typename}}) Set(s string) error {
+	v, err := {{$parse}}(s)
+	if err != nil {
+		return err
+	}
+	*r = v
+	return nil
+}
+{{end}}
+
+// {{$parse}} returns the {{$typename}} named s{{if $.IgnoreCase}}, ignoring case{{end}}.
+{{- if $.Overridable}}
+// Strings in {{$typename}}UnmarshalMap are looked up first, matching exactly.
+{{- end}}
+func {{$parse}}(s string) ({{$typename}}, error) {
+{{- if $.Overridable}}
+	if v, ok := {{$typename}}UnmarshalMap[s]; ok {
+		return v, nil
+	}
+{{- end}}
+{{- if eq $.Lookup "switch"}}
+	var v {{$typename}}
+	ok := true
+	switch s {
+	{{- range $values}}{{if not .Repeat}}
+	case {{printf "%q" .Str}}:
+		v = {{.Name}}
+	{{- end}}{{end}}
+	default:
+		ok = false
+	}
+{{- else if eq $.Lookup "binary"}}
+	var v {{$typename}}
+	i := sort.SearchStrings(_{{$typename}}SortedNames[:], s)
+	ok := i < len(_{{$typename}}SortedNames) && _{{$typename}}SortedNames[i] == s
+	if ok {
+		v = _{{$typename}}SortedValues[i]
+	}
+{{- else}}
+	v, ok := {{$nameToValue}}[s]
+{{- end}}
+{{- if $.IgnoreCase}}
+	if !ok {
+{{- if eq $.Lookup "switch"}}
+		switch {
+		{{- range $values}}{{if not .Repeat}}
+		case strings.EqualFold(s, {{printf "%q" .Str}}):
+			return {{.Name}}, nil
+		{{- end}}{{end}}
+		}
+{{- else if eq $.Lookup "binary"}}
+		for i, name := range _{{$typename}}SortedNames {
+			if strings.EqualFold(name, s) {
+				return _{{$typename}}SortedValues[i], nil
+			}
+		}
+{{- else}}
+		for name, value := range {{$nameToValue}} {
+			if strings.EqualFold(name, s) {
+				return value, nil
+			}
+		}
+{{- end}}
+	}
+{{- end}}
+	if !ok {
+{{- if $.ErrType}}
+		return v, &Invalid{{$typename}}Error{Value: s}
+{{- else}}
+		return v, fmt.Errorf("invalid {{$typename}} %q: valid values are %s", s, {{printf "%q" (list $values)}})
+{{- end}}
+	}
+	return v, nil
+}
+
+{{if $.MustParse}}
+// Must{{$parse}} is like {{$parse}} but panics if s names no {{$typename}}.
+func Must{{$parse}}(s string) {{$typename}} {
+	v, err := {{$parse}}(s)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+{{end}}
+
+{{if $.ErrType}}
+// Invalid{{$typename}}Error is returned when unmarshaling a string naming no {{$typename}}.
+type Invalid{{$typename}}Error struct {
+	// Value is the string naming no {{$typename}}.
+{{- if $.Validate}}
+	// For errors of Validate it is the invalid value{{if not $type.String}} in decimal{{end}}.
+{{- end}}
+	Value string
+{{- if and $.YAML (not $sigs) (not $v2)}}
+	// Line and Column locate the offending node for errors of UnmarshalYAML
+	// and are 0 otherwise.
+	Line, Column int
+{{- end}}
+}
+
+func (e *Invalid{{$typename}}Error) Error() string {
+{{- if and $.YAML (not $sigs) (not $v2)}}
+	if e.Line != 0 {
+		return fmt.Sprintf("invalid {{$typename}} %q at line %d, column %d: valid values are %s", e.Value, e.Line, e.Column, {{printf "%q" (list $values)}})
+	}
+{{- end}}
+	return fmt.Sprintf("invalid {{$typename}} %q: valid values are %s", e.Value, {{printf "%q" (list $values)}})
+}
+{{end}}
+
+{{end}}
+`))
+
+// generatedExampleTmpl generates an example for every type marshaling its
+// first two values to YAML and back, its output worked out with
+// gopkg.in/yaml.v3 at generation.
+var generatedExampleTmpl = template.Must(template.New("generated_example").Funcs(funcs).Parse(header + `
+package {{.PackageName}}
+
+import (
+    "fmt"
+
+    {{if ne .YAMLName "yaml"}}{{.YAMLName}} {{end}}"gopkg.in/yaml.v3"
+)
 
+{{range $typename, $values := .TypesAndValues}}
+{{- $examples := distinct $values 2}}
+// Example{{$typename}} shows {{$typename}} values marshaled to YAML and unmarshaled back.
+func Example{{$typename}}() {
+    for _, v := range []{{$typename}}{ {{- range $i, $v := $examples}}{{if $i}}, {{end}}{{$v.Name}}{{end -}} } {
+{{- if $.PtrMarshal}}
+        b, err := {{$.YAMLName}}.Marshal(&v)
+{{- else}}
+        b, err := {{$.YAMLName}}.Marshal(v)
+{{- end}}
+        if err != nil {
+            fmt.Println(err)
+            return
+        }
+        var u {{$typename}}
+        if err := {{$.YAMLName}}.Unmarshal(b, &u); err != nil {
+            fmt.Println(err)
+            return
+        }
+        fmt.Print(string(b))
+        fmt.Println(u == v)
+    }
+    // Output:
+{{- range $examples}}
+{{- range yamlLines .Str $.YAMLTag}}
+    // {{.}}
+{{- end}}
+    // true
+{{- end}}
+}
+{{end}}
+`))
+
+var generatedTestTmpl = template.Must(template.New("generated_test").Funcs(funcs).Parse(header + `
+package {{.PackageName}}
+
+import (
+    "testing"
+
+{{if eq .YAMLPackage "sigs"}}
+    {{if ne .YAMLName "yaml"}}{{.YAMLName}} {{end}}"sigs.k8s.io/yaml"
+{{- else if eq .YAMLPackage "both"}}
+    {{if ne .YAMLName "yaml"}}{{.YAMLName}} {{end}}"gopkg.in/yaml.v3"
+{{- else}}
+    {{if ne .YAMLName "yaml"}}{{.YAMLName}} {{end}}"gopkg.in/yaml.{{.YAMLPackage}}"
+{{- end}}
+)
+
+{{range $typename, $values := .TypesAndValues}}
+func Test{{$typename}}YAMLRoundTrip(t *testing.T) {
+    for _, tt := range []struct {
+        name  string
+        value {{$typename}}
+    }{
+        {{range $values}}{ {{printf "%q" .Name}}, {{.Name}} },
+        {{end}}
+    } {
+{{- if $.PtrMarshal}}
+        b, err := {{$.YAMLName}}.Marshal(&tt.value)
+{{- else}}
+        b, err := {{$.YAMLName}}.Marshal(tt.value)
+{{- end}}
+        if err != nil {
+            t.Errorf("marshaling %s: %v", tt.name, err)
+            continue
+        }
+        var v {{$typename}}
+        if err := {{$.YAMLName}}.Unmarshal(b, &v); err != nil {
+            t.Errorf("unmarshaling %s from %q: %v", tt.name, b, err)
+        } else if v != tt.value {
+            t.Errorf("%s is unmarshaled from %q as another value", tt.name, b)
+        }
+    }
+}
+
+func Test{{$typename}}YAMLUnknown(t *testing.T) {
+    b, err := {{$.YAMLName}}.Marshal({{printf "%q" (unknown $typename $values)}})
+    if err != nil {
+        t.Fatal(err)
+    }
+    var v {{$typename}}
+{{- with index $.Fallbacks $typename}}
+    if err := {{$.YAMLName}}.Unmarshal(b, &v); err != nil || v != {{.}} {
+        t.Errorf("unmarshaling %q gives %v, %v, not {{.}}", b, v, err)
+    }
+{{- else}}
+    if err := {{$.YAMLName}}.Unmarshal(b, &v); err == nil {
+        t.Errorf("unmarshaling %q succeeded", b)
+    }
+{{- end}}
+}
 {{end}}
 `))
@@ -0,0 +1,76 @@
+// Copyright 2020 igrmk. All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to writing, software distributed
+// under the License is distributed on a "AS IS" BASIS, WITHOUT WARRANTIES OR
+// CONDITIONS OF ANY KIND, either express or implied.
+//
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// transforms maps the values accepted by -transform to the functions
+// transforming the names of the constants.
+var transforms = map[string]func(string) string{
+	"snake": func(s string) string { return strings.ToLower(strings.Join(splitWords(s), "_")) },
+	"kebab": func(s string) string { return strings.ToLower(strings.Join(splitWords(s), "-")) },
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"camel": camelCase,
+}
+
+// splitWords splits a Go identifier into words. Words are separated by
+// underscores and by changes of case, so that an acronym such as HTTP in
+// HTTPStatus forms a word of its own. Digits stick to the preceding word.
+func splitWords(s string) []string {
+	var words []string
+	var word []rune
+	runes := []rune(s)
+	for i, r := range runes {
+		if r == '_' {
+			if len(word) > 0 {
+				words = append(words, string(word))
+				word = nil
+			}
+			continue
+		}
+		if unicode.IsUpper(r) && len(word) > 0 {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if !unicode.IsUpper(prev) || nextLower {
+				words = append(words, string(word))
+				word = nil
+			}
+		}
+		word = append(word, r)
+	}
+	if len(word) > 0 {
+		words = append(words, string(word))
+	}
+	return words
+}
+
+// camelCase joins the words of s lower-casing the first one and title-casing
+// the rest, so HTTPStatus becomes httpStatus.
+func camelCase(s string) string {
+	words := splitWords(s)
+	for i, w := range words {
+		w = strings.ToLower(w)
+		if i > 0 {
+			runes := []rune(w)
+			runes[0] = unicode.ToUpper(runes[0])
+			w = string(runes)
+		}
+		words[i] = w
+	}
+	return strings.Join(words, "")
+}
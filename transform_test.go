@@ -0,0 +1,36 @@
+// Copyright 2020 igrmk. All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to writing, software distributed
+// under the License is distributed on a "AS IS" BASIS, WITHOUT WARRANTIES OR
+// CONDITIONS OF ANY KIND, either express or implied.
+//
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import "testing"
+
+func TestTransforms(t *testing.T) {
+	for _, tt := range []struct {
+		name, transform, want string
+	}{
+		{"HTTPStatus", "snake", "http_status"},
+		{"HTTPStatus", "kebab", "http-status"},
+		{"HTTPStatus", "lower", "httpstatus"},
+		{"HTTPStatus", "upper", "HTTPSTATUS"},
+		{"HTTPStatus", "camel", "httpStatus"},
+		{"PillAspirin", "snake", "pill_aspirin"},
+		{"Level2Up", "kebab", "level2-up"},
+		{"Foo_Bar", "camel", "fooBar"},
+		{"ID", "snake", "id"},
+	} {
+		if got := transforms[tt.transform](tt.name); got != tt.want {
+			t.Errorf("%s transformation of %s gives %s, want %s", tt.transform, tt.name, got, tt.want)
+		}
+	}
+}
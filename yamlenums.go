@@ -14,12 +14,11 @@
 
 // YAMLenums is a tool to automate the creation of methods that satisfy the
 // fmt.Stringer, yaml.Marshaler and yaml.Unmarshaler interfaces.
-// Given the name of a (signed or unsigned) integer type T that has constants
-// defined, yamlenums will create a new self-contained Go source file implementing
+// Given the name of a (signed or unsigned) integer or string type T that has
+// constants defined, yamlenums will create a new self-contained Go source file implementing
 //
-//  func (t T) String() string
-//  func (t T) MarshalYAML() ([]byte, error)
-//  func (t *T) UnmarshalYAML([]byte) error
+//  func (t T) MarshalYAML() (interface{}, error)
+//  func (t *T) UnmarshalYAML(value *yaml.Node) error
 //
 // The file is created in the same package and directory as the package that defines T.
 // It has helpful defaults designed for use with go generate.
@@ -48,114 +47,1642 @@
 // in the same directory will create the file pill_yamlenums.go, in package painkiller,
 // containing a definition of
 //
-//  func (r Pill) String() string
-//  func (r Pill) MarshalYAML() ([]byte, error)
-//  func (r *Pill) UnmarshalYAML([]byte) error
+//  func (r Pill) MarshalYAML() (interface{}, error)
+//  func (r *Pill) UnmarshalYAML(value *yaml.Node) error
 //
 // That method will translate the value of a Pill constant to the string representation
-// of the respective constant name, so that the call fmt.Print(painkiller.Aspirin) will
-// print the string "Aspirin".
+// of the respective constant name, so that the call yaml.Marshal(painkiller.Aspirin) will
+// produce the string "Aspirin".
 //
 // Typically this process would be run using go generate, like this:
 //
 //	//go:generate yamlenums -type=Pill
 //
 // If multiple constants have the same value, the lexically first matching name will
-// be used (in the example, Acetaminophen will print as "Paracetamol").
+// be used (in the example, Acetaminophen will print as "Paracetamol"), while
+// unmarshaling accepts every name.
+// With -dupes=warn such constants are warned about, and with -dupes=error they
+// make yamlenums fail.
+//
+// Constants whose doc comments have a paragraph starting with "Deprecated:"
+// are still unmarshaled, but their values are marshaled to the first name
+// declared for them that isn't deprecated, if any. Everything else taking the
+// first name of a value, such as String, AllPill or PillName, prefers it too.
+// With -deprecated=warn every deprecated constant is warned about, and with
+// -deprecated=reject the deprecated constants are left out altogether, so
+// their names fail to unmarshal.
 //
 // With no arguments, it processes the package in the current directory.
 // Otherwise, the arguments must name a single directory holding a Go package
-// or a set of Go source files that represent a single Go package.
+// or a set of Go source files that represent a single Go package. The output
+// files are written to the directory holding all the given source files. The
+// _test.go files are skipped unless the -tests flag is given. Files excluded by
+// build constraints are skipped too; the -tags flag takes a comma-separated
+// list of additional build tags to apply. With -gofile only the constants
+// declared in the file named by $GOFILE, which go generate sets to the file
+// holding the directive, are taken into account. The -verbose flag logs the
+// files parsed and the constants found, which helps finding out why a constant
+// is missing.
+//
+// A package failing to compile can't be parsed, even if the errors have
+// nothing to do with the constants, as in the middle of a refactoring. With
+// -best-effort yamlenums tolerates errors outside the declarations of the
+// constants of the types, and -verbose logs the errors ignored. Errors within
+// them still make it fail, as the constants may be wrong or missing.
 //
 // The -type flag accepts a comma-separated list of types so a single run can
-// generate methods for multiple types. The default output file is
-// t_yamlenums.go, where t is the lower-cased name of the first type listed.
-// The suffix can be overridden with the -suffix flag and a prefix may be added
-// with the -prefix flag.
+// generate methods for multiple types. Empty entries are skipped, so lists
+// assembled by scripts may have stray commas, and types listed more than once
+// are generated once, in the order first listed. Long lists can go to a file
+// named by -typefile instead, one type per line, blank lines and # comments
+// being ignored; its types add to the ones of -type. Types missing from the
+// package are reported all at once. Types may be qualified by the package name,
+// as in -type=painkiller.Pill, which must name the package parsed.
+//
+// With -all-types instead, methods are generated for every integer and string
+// type of the package having constants. The -list flag prints these types and
+// their constants without generating anything, so no -type is needed.
+//
+// The default output file is t_yamlenums.go, where t is the lower-cased name of
+// the first type listed. The suffix can be overridden with the -suffix flag and
+// a prefix may be added with the -prefix flag. Both are lower-cased along with
+// the type name and may have dots or no underscore, so -suffix=_gen and
+// -suffix=.Enum give pill_gen.go and pill.enum.go, and .go is appended unless
+// the suffix ends with it already. They can't both be empty, as the output
+// could then replace the source declaring the type. With -filecase=snake the
+// type name is snake-cased instead, so HTTPStatus gives
+// http_status_yamlenums.go rather than httpstatus_yamlenums.go.
+//
+// The -single flag generates one file containing the methods of all the listed
+// types, named after the first one. The -output flag names the exact output
+// file instead; its parent directories are created if needed, and -output=-
+// writes the generated source to the standard output. The -dir flag writes the
+// output files to another directory, provided the package there declares the
+// types too.
+//
+// The -pkg flag sets the package clause of the generated files, which is the
+// one of the parsed package otherwise. The -buildtag flag constrains the
+// generated files, so -buildtag=yaml makes them compiled only if the yaml tag
+// is given and -buildtag=!noyaml unless the noyaml tag is given. The -license
+// flag names a text file, such as a copyright notice, put atop the generated
+// files as line comments. The header records the command line unless -nocmd
+// is given.
+//
+// The -go flag sets the Go version the generated code targets, which is the one
+// of the go directive of the module otherwise. Any Go 1 version is accepted,
+// the code avoiding newer language and library features such as any and
+// wrapping errors with %w, so the built-in template generates the same code for
+// all of them, while custom templates may tell the versions apart. Go
+// directives yamlenums can't make sense of count as old versions for them.
+//
+// With -dryrun nothing is written; the paths and sizes of the files that would
+// be written are logged instead. Files whose content is up to date are not
+// rewritten, so their modification times are kept, unless -force is given.
+//
+// New files get the permissions 0644 less the umask, and rewritten ones keep
+// theirs. The -perm flag sets the permissions of the written files in octal
+// instead, whatever the umask; -perm=0444 makes them read-only, which
+// discourages editing them, and yamlenums rewrites them all the same.
+//
+// The generated code is formatted with gofmt, which takes most of the time
+// for huge enums; -noformat skips it, leaving the code as the template
+// produces it.
+//
+// yamlenums stops at the first type failing, such as a type having no
+// constants. With -keepgoing it logs the error and skips the type instead,
+// generating the code for the rest, and exits with status 1 at the end, which
+// suits CI runs covering many types.
+//
+// Unless -parse=false is given, a function
+//
+//	func ParsePill(s string) (Pill, error)
+//
+// is generated as well. It returns the constant named s or an error listing
+// the valid names if there is no such constant. The -mustparse flag adds
+// MustParsePill, which panics instead of returning the error, for names known
+// to be valid. The -stringer flag adds a String method returning the name
+// of the constant, or Pill(12) for values having no name.
+//
+// The -json flag adds MarshalJSON and UnmarshalJSON methods using the same
+// names, and the -text flag adds MarshalText and UnmarshalText methods
+// satisfying encoding.TextMarshaler and encoding.TextUnmarshaler. These are
+// the methods encoding/json marshals map keys with, so a map[Pill]int has
+// names as keys in JSON with -text only, while go-yaml uses the YAML methods
+// for map keys too, unless -ptrmarshal is given. The -xml
+// flag adds MarshalXML and UnmarshalXML methods taking the names as the
+// character data of elements, so empty elements fail to unmarshal unless
+// -emptyzero is given. The -sql
+// flag adds Value and Scan methods satisfying driver.Valuer and sql.Scanner,
+// storing the names as text and scanning NULL as the zero value. The
+// -flagvalue flag adds Set and String methods, so a *Pill satisfies flag.Value
+// and can be passed to flag.Var. The YAML methods can be turned off with
+// -yaml=false.
+//
+// The YAML methods target gopkg.in/yaml.v3 by default. With -yamlpkg=v2 they
+// target gopkg.in/yaml.v2 instead, UnmarshalYAML taking an unmarshal function
+// rather than a *yaml.Node. For v3 UnmarshalYAML rejects mappings and
+// sequences right away, and its errors tell the line and the column of the
+// offending node. It takes the strings of scalar nodes as they are, sparing
+// the reflection and the allocations of Decode, which matters for large
+// configs holding many enum fields. With -yamlpkg=both the methods take the
+// v2 form, which v3 still accepts, so they work with whichever go-yaml the
+// program uses, and -gentests tests them with v3. With -yamlpkg=sigs the JSON
+// methods are generated instead of the YAML ones, since sigs.k8s.io/yaml
+// converts YAML to JSON and relies on json.Marshaler and json.Unmarshaler.
+//
+// The generated files import the YAML package under the name the files of the
+// package already import it under, so a package importing gopkg.in/yaml.v3 as
+// yamlv3 gets generated code referring to yamlv3. If none of them imports it,
+// the name is yaml, or goyaml if the package declares yaml itself.
+//
+// MarshalYAML fails for values having no name, such as Pill(12), unless
+// -marshalunknown=int is given. Then such values of integer types are
+// marshaled as integers, which suits producers newer than the consumers.
+//
+// With -marshalint MarshalYAML produces the integer values of the constants
+// rather than their names, still failing for values having no name unless
+// -marshalunknown=int is given, while UnmarshalYAML accepts both as with
+// -acceptint. That way new configs may use the names while old consumers
+// keep getting integers. Every constant survives a round trip, whether it's
+// written as a name or an integer.
+//
+// With -yamltag=!pill MarshalYAML produces *yaml.Node scalars tagged !pill, so
+// the YAML reads "pill: !pill Aspirin". UnmarshalYAML accepts the names with the
+// tag or without it. The flag needs -yamlpkg=v3.
+//
+// With -ptrmarshal MarshalYAML has a pointer receiver, so only *Pill satisfies
+// yaml.Marshaler. go-yaml then marshals Pill values, such as struct fields of
+// type Pill, as plain integers or strings, calling the method only for *Pill.
+//
+// With -ignorecase the names are matched case-insensitively when unmarshaling,
+// so "aspirin" and "ASPIRIN" both decode to Aspirin. Marshaling still produces
+// the name of the constant as it is. Constants of different values whose
+// strings differ only in case are rejected then, as they can't be told apart.
+// With -acceptint UnmarshalYAML also accepts
+// the integer value of a constant, which eases migrating from integer-based
+// configs. With -emptyzero UnmarshalYAML decodes empty scalars, as in
+// pill: "", to the zero value instead of failing, which has to be the value of
+// a constant. go-yaml never calls it for nulls, which leave the value as it
+// is either way. The empty string is matched as it is, -ignorecase having no
+// say in it, and the zero value marshals to the first name declared for it, as
+// aliases do. With -fallback=Unknown unknown
+// names unmarshal to the constant Unknown rather than failing, which suits
+// readers of configs written by newer producers. This goes for UnmarshalJSON,
+// UnmarshalText and UnmarshalXML too, while ParsePill still fails. The flag
+// takes a constant per type at most; types not having one listed fail as
+// usual.
+//
+// With -bitflags the constants are taken as bits to combine, so every one must
+// be a power of two or zero. MarshalYAML then produces a sequence of the names
+// of the bits set, in the order the constants are declared, and UnmarshalYAML
+// expects such a sequence, OR-ing the bits named:
+//
+//	perms: [Read, Exec]
+//
+// With -flagstring as well, a combination is marshaled to a single string
+// instead, the names joined with the separator given by -flagsep, "|" by
+// default:
+//
+//	perms: Read|Exec
+//
+// The flag needs integer types and the go-yaml methods, and it can't be
+// combined with -acceptint, -marshalint or -marshalunknown=int.
+//
+// The -valid flag adds an IsValid method reporting whether a value is one of
+// the constants, which is handy for values converted from integers. The
+// -validate flag adds a Validate method returning an error for such values
+// instead, the one -errtype makes generated if given. The -all
+// flag adds a function
+//
+//	func AllPill() []Pill
+//
+// returning the values of the constants in the order they are declared. Every
+// value is listed once, under the first name declared for it. With -ordinal an
+// Ordinal method returns the position of a value in that list and the function
+// PillFromOrdinal returns the value at a position.
+//
+// With -names a function
+//
+//	func PillNames() []string
+//
+// returns the strings the values are marshaled to in the order the constants
+// are declared, for help texts and validation messages. Every value is listed
+// once, under the first name declared for it, with -trimprefix, -transform and
+// the like applied, or as returned by a String method declared for the type.
+// With -docs the doc comments of the constants are carried into the lists of
+// both functions, making them longer but easier to read.
+//
+// With -namefunc a function
+//
+//	func PillName(v Pill) string
+//
+// returns the name of the constant declared first for v as it is in the
+// source, unaffected by -transform and the like, or "" for values of no
+// constant. Being no method, it suits logging raw values off the wire.
+//
+// With -navigate the methods Next and Prev return the value declared after and
+// before the receiver, with false for the last and the first value and for
+// values of no constant. Aliases don't count as separate positions.
+//
+// The constants named by strings are looked up in a map by default. With
+// -lookup=switch a switch statement is generated instead, and with
+// -lookup=binary the strings are searched in a sorted array with
+// sort.SearchStrings. Neither of the two needs a map built at init, but both
+// use the strings known at generation time, ignoring String methods declared
+// for the type.
+//
+// The maps between the strings and the values are unexported by default. With
+// -exportmaps they are exported as PillNameToValue and PillValueToName, for
+// building documentation or validation tables at run time; they are generated
+// whatever the lookup, and the methods share them, so they must not be
+// modified.
+//
+// With -overridable the variables PillMarshalMap and PillUnmarshalMap, nil by
+// default, are generated for remapping the strings at run time, say for
+// localized or versioned configs. The marshaling methods and String look a
+// value up in PillMarshalMap first, and ParsePill, and so the unmarshaling
+// methods, looks a string up in PillUnmarshalMap first, falling back to the
+// generated strings when missing. The maps are read without locking, so they
+// must be set before use, such as in init, and not modified afterwards.
+//
+// With -errtype a type InvalidPillError holding the offending string is
+// generated and returned for strings naming no constant, so callers can
+// inspect it with errors.As. For v3 its Line and Column tell the position of
+// the node UnmarshalYAML failed for.
+//
+// With -linecomment the text of the line comment following a constant is used
+// as its string instead of its name, as with stringer -linecomment. The
+// -trimprefix flag takes a comma-separated list of prefixes; the first one
+// matching the name of a constant is trimmed from its string, so with
+// -trimprefix=Pill the constant PillAspirin is marshaled to "Aspirin". The
+// -trimsuffix flag does the same with suffixes. For string types the values of
+// the constants are used instead of their names. The -transform flag changes the
+// case of the trimmed names: snake, kebab, lower, upper and camel turn
+// HTTPStatus into http_status, http-status, httpstatus, HTTPSTATUS and
+// httpStatus respectively.
+//
+// With -schema a JSON Schema listing the strings of the constants, aliases
+// included, is written for every type next to the output file, as
+// pill.schema.json for Pill, or to the standard output with -output=-. With
+// -bitflags it describes sequences of the strings. It can't be used with
+// -marshalint, whose integers it doesn't describe.
+//
+// With -example a test file, named like the output file with _example_test
+// added, gets an ExamplePill function marshaling the first two values of Pill
+// to YAML and back, so go doc shows how the type is used. Its output is worked
+// out at generation, which rules out types having String methods of their own.
+// The flag needs -yamlpkg=v3.
+//
+// With -gentests a test file, named like the output file with _test added, is
+// generated too. It checks that every constant survives a YAML round trip and
+// that unknown strings are rejected.
+//
+// With -verify the same round trip is checked at generation, what MarshalYAML
+// produces for every constant being taken apart and looked up as the generated
+// UnmarshalYAML does, so generation fails naming any constant whose value would
+// be lost. That catches the integers of -marshalint that are the strings of
+// other constants, as with -linecomment, and the strings of -flagstring having
+// spaces trimmed when unmarshaling. Types having String methods of their own
+// can't be checked this way.
+//
+// The -template flag names a text/template file to generate the code with
+// instead of the built-in template. The template is executed with the data
+// described in the README and its output is formatted with gofmt. With
+// -fiximports the imports are fixed as goimports does as well, adding the
+// packages the template refers to and removing the unused ones, so custom
+// templates needn't maintain their import blocks.
+//
+// Finally, the string of a single constant can be set with a comment like
+//
+//	Paracetamol // yamlenums:"acetaminophen"
+//
+// which takes precedence over all the flags above.
 //
 package main
 
 import (
 	"bytes"
 	"flag"
+	"fmt"
+	"go/constant"
 	"go/format"
+	"go/token"
+	"go/types"
 	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"text/template"
 
 	"github.com/igrmk/yamlenums/parser"
+	"golang.org/x/tools/imports"
 )
 
 var (
-	typeNames    = flag.String("type", "", "comma-separated list of type names; must be set")
+	typeNames    = flag.String("type", "", "comma-separated list of type names; must be set unless -all-types is")
+	typeFile     = flag.String("typefile", "", "file listing type names one per line, # starting comments; adds to -type")
+	allTypes     = flag.Bool("all-types", false, "generate methods for every integer and string type having constants")
+	listOnly     = flag.Bool("list", false, "print every integer and string type having constants and its constants, generating nothing")
 	outputPrefix = flag.String("prefix", "", "prefix to be added to the output file")
 	outputSuffix = flag.String("suffix", "_yamlenums", "suffix to be added to the output file")
+	fileCase     = flag.String("filecase", "lower", "case of the type name in output file names: lower or snake")
+	outputPath   = flag.String("output", "", "output file path, - for standard output; overrides -prefix and -suffix")
+	force        = flag.Bool("force", false, "write the output files even if their content is up to date")
+	perm         = flag.String("perm", "", "octal permissions of the written files, such as 0444 for read-only ones, regardless of the umask")
+	dryRun       = flag.Bool("dryrun", false, "report the files that would be written instead of writing them")
+	license      = flag.String("license", "", "text file with a header, such as a license, to put atop the generated files as comments")
+	buildTag     = flag.String("buildtag", "", "build tag, possibly negated with !, constraining the generated files")
+	goVersion    = flag.String("go", "", "Go version, such as 1.12, the generated code targets; defaults to the go directive of the module")
+	outputDir    = flag.String("dir", "", "directory to write the output files to; its package must declare the types")
+	packageName  = flag.String("pkg", "", "package name of the generated code; defaults to the name of the parsed package")
+	single       = flag.Bool("single", false, "generate a single file for all the types")
+	parse        = flag.Bool("parse", true, "generate a ParseT function for every type T")
+	mustParse    = flag.Bool("mustparse", false, "generate a MustParseT function panicking on errors for every type T; implies -parse")
+	stringer     = flag.Bool("stringer", false, "generate String methods")
+	genYAML      = flag.Bool("yaml", true, "generate MarshalYAML and UnmarshalYAML methods")
+	yamlPackage  = flag.String("yamlpkg", "v3", "YAML package to generate the methods for: v2 or v3 of go-yaml, both of them, or sigs for sigs.k8s.io/yaml")
+	onUnknown    = flag.String("marshalunknown", "error", "what MarshalYAML does with values having no name: error or int")
+	genJSON      = flag.Bool("json", false, "generate MarshalJSON and UnmarshalJSON methods")
+	genText      = flag.Bool("text", false, "generate MarshalText and UnmarshalText methods")
+	genXML       = flag.Bool("xml", false, "generate MarshalXML and UnmarshalXML methods")
+	genSQL       = flag.Bool("sql", false, "generate Value and Scan methods for database/sql")
+	flagValue    = flag.Bool("flagvalue", false, "generate Set and String methods satisfying flag.Value")
+	exportMaps   = flag.Bool("exportmaps", false, "export the maps between the strings and the values of every type T as TNameToValue and TValueToName")
+	overridable  = flag.Bool("overridable", false, "generate variables TMarshalMap and TUnmarshalMap of every type T the methods consult before the generated strings")
+	lookup       = flag.String("lookup", "map", "lookup of the constants named by strings: map, switch or binary")
+	ignoreCase   = flag.Bool("ignorecase", false, "match names case-insensitively when unmarshaling")
+	emptyZero    = flag.Bool("emptyzero", false, "unmarshal empty and null YAML scalars and empty XML elements to the zero value")
+	fallback     = flag.String("fallback", "", "comma-separated list of constants unknown strings unmarshal to, one per type at most")
+	bitFlags     = flag.Bool("bitflags", false, "marshal combinations of power-of-two constants to YAML sequences of their names")
+	flagString   = flag.Bool("flagstring", false, "marshal -bitflags combinations to single strings of names joined by -flagsep")
+	flagSep      = flag.String("flagsep", "|", "separator of the names in strings of -flagstring")
+	yamlTag      = flag.String("yamltag", "", "tag of the YAML scalars MarshalYAML produces, such as !pill; needs -yamlpkg=v3")
+	ptrMarshal   = flag.Bool("ptrmarshal", false, "generate MarshalYAML methods with pointer receivers")
+	marshalInt   = flag.Bool("marshalint", false, "marshal the integer values of the constants in MarshalYAML; implies -acceptint")
+	acceptInt    = flag.Bool("acceptint", false, "accept integer values of the constants in UnmarshalYAML")
+	validate     = flag.Bool("validate", false, "generate Validate methods returning errors for invalid values")
+	valid        = flag.Bool("valid", false, "generate IsValid methods")
+	errType      = flag.Bool("errtype", false, "generate InvalidTError types returned for unknown strings")
+	nameFunc     = flag.Bool("namefunc", false, "generate a TName function returning the name of the constant for every type T")
+	navigate     = flag.Bool("navigate", false, "generate Next and Prev methods for every type")
+	ordinal      = flag.Bool("ordinal", false, "generate Ordinal methods and TFromOrdinal functions for every type T")
+	all          = flag.Bool("all", false, "generate AllT functions listing the values of every type T")
+	names        = flag.Bool("names", false, "generate TNames functions listing the strings of the values of every type T")
+	docs         = flag.Bool("docs", false, "carry the doc comments of the constants into the lists of AllT and TNames")
+	deprecation  = flag.String("deprecated", "accept", "handling of constants having Deprecated: doc comments: accept, warn or reject")
+	dupes        = flag.String("dupes", "first", "handling of constants sharing a value: first, warn or error")
+	lineComment  = flag.Bool("linecomment", false, "use line comment text as the string for a constant when present")
+	trimPrefix   = flag.String("trimprefix", "", "comma-separated list of prefixes to trim from the names of the constants")
+	trimSuffix   = flag.String("trimsuffix", "", "comma-separated list of suffixes to trim from the names of the constants")
+	transform    = flag.String("transform", "", "transformation of the names of the constants: snake, kebab, lower, upper or camel")
+	templateFile = flag.String("template", "", "text/template file to generate the code with instead of the built-in template")
+	goFile       = flag.Bool("gofile", false, "take constants from the file named by $GOFILE only, as set by go generate")
+	genSchema    = flag.Bool("schema", false, "write a JSON Schema listing the strings of every type T to t.schema.json")
+	genExample   = flag.Bool("example", false, "generate Example functions marshaling the first two values of every type")
+	genTests     = flag.Bool("gentests", false, "generate tests round-tripping the values through the YAML methods")
+	verify       = flag.Bool("verify", false, "check at generation that every constant unmarshals back from what MarshalYAML produces for it")
+	keepGoing    = flag.Bool("keepgoing", false, "skip the types failing, generating the code for the rest, and exit with status 1 at the end")
+	noCmd        = flag.Bool("nocmd", false, "leave the command line out of the header of the generated files")
+	fixImports   = flag.Bool("fiximports", false, "add missing and remove unused imports of the generated code like goimports")
+	noFormat     = flag.Bool("noformat", false, "write the code as the template produces it, skipping gofmt")
+	verbose      = flag.Bool("verbose", false, "log the parsed files and the constants found")
+	tests        = flag.Bool("tests", false, "parse the _test.go files of the package too")
+	bestEffort   = flag.Bool("best-effort", false, "tolerate errors of the package outside the declarations of the constants")
+	buildTags    = flag.String("tags", "", "comma-separated list of build tags to apply when parsing")
 )
 
+// fileMode is the mode of the new files, less the umask unless -perm sets it.
+var fileMode os.FileMode = 0644
+
 func main() {
 	flag.Parse()
-	if len(*typeNames) == 0 {
+	if *listOnly && (len(*typeNames) > 0 || len(*typeFile) > 0 || *allTypes) {
+		log.Fatalf("the flag -list can't be used with -type, -typefile or -all-types")
+	}
+	if len(*typeNames) == 0 && len(*typeFile) == 0 && !*allTypes && !*listOnly {
 		log.Fatalf("the flag -type must be set")
 	}
-	types := strings.Split(*typeNames, ",")
+	if len(*typeNames) > 0 && *allTypes {
+		log.Fatalf("the flags -type and -all-types can't be used together")
+	}
+	if len(*typeFile) > 0 && *allTypes {
+		log.Fatalf("the flags -typefile and -all-types can't be used together")
+	}
+	if *yamlPackage != "v2" && *yamlPackage != "v3" && *yamlPackage != "both" && *yamlPackage != "sigs" {
+		log.Fatalf("unknown yaml package version %q", *yamlPackage)
+	}
+	if len(*perm) > 0 {
+		mode, err := parsePerm(*perm)
+		if err != nil {
+			log.Fatalf("the flag -perm: %v", err)
+		}
+		fileMode = mode
+	}
+	if len(*goVersion) > 0 && !goVersionRE.MatchString(*goVersion) {
+		log.Fatalf("malformed Go version %q", *goVersion)
+	}
+	if len(*buildTag) > 0 && !buildTagRE.MatchString(*buildTag) {
+		log.Fatalf("malformed build tag %q", *buildTag)
+	}
+	if *genTests && !*genYAML {
+		log.Fatalf("the flag -gentests needs the YAML methods generated")
+	}
+	if *genTests && *outputPath == "-" {
+		log.Fatalf("the flag -gentests can't be used with -output=-")
+	}
+	if *genExample && (!*genYAML || *yamlPackage != "v3") {
+		log.Fatalf("the flag -example needs the YAML methods generated for gopkg.in/yaml.v3")
+	}
+	if *genExample && (*bitFlags || *marshalInt) {
+		log.Fatalf("the flag -example can't be used with -bitflags or -marshalint")
+	}
+	if *genExample && *outputPath == "-" {
+		log.Fatalf("the flag -example can't be used with -output=-")
+	}
+	if *dupes != "first" && *dupes != "warn" && *dupes != "error" {
+		log.Fatalf("unknown handling of constants sharing a value %q", *dupes)
+	}
+	if *onUnknown != "error" && *onUnknown != "int" {
+		log.Fatalf("unknown marshaling of values having no name %q", *onUnknown)
+	}
+	if *bitFlags && (!*genYAML || *yamlPackage == "sigs") {
+		log.Fatalf("the flag -bitflags needs the YAML methods generated for go-yaml")
+	}
+	if *bitFlags && (*acceptInt || *marshalInt || *onUnknown == "int") {
+		log.Fatalf("the flag -bitflags can't be used with -acceptint, -marshalint or -marshalunknown=int")
+	}
+	if *marshalInt && (!*genYAML || *yamlPackage == "sigs") {
+		log.Fatalf("the flag -marshalint needs the YAML methods generated for go-yaml")
+	}
+	if len(*yamlTag) > 0 && (!*genYAML || *yamlPackage != "v3") {
+		log.Fatalf("the flag -yamltag needs the YAML methods generated for gopkg.in/yaml.v3")
+	}
+	if len(*yamlTag) > 0 && (*bitFlags || *marshalInt) {
+		log.Fatalf("the flag -yamltag can't be used with -bitflags or -marshalint")
+	}
+	if len(*yamlTag) > 0 && !yamlTagRE.MatchString(*yamlTag) {
+		log.Fatalf("malformed YAML tag %q", *yamlTag)
+	}
+	if *ptrMarshal && (!*genYAML || *yamlPackage == "sigs") {
+		log.Fatalf("the flag -ptrmarshal needs the YAML methods generated for go-yaml")
+	}
+	if *genSchema && *flagString {
+		log.Fatalf("the flag -schema can't describe the strings of -flagstring")
+	}
+	if *genSchema && *marshalInt {
+		log.Fatalf("the flag -schema can't describe the integers of -marshalint")
+	}
+	if *flagString && !*bitFlags {
+		log.Fatalf("the flag -flagstring needs -bitflags")
+	}
+	if len(*fallback) > 0 && *bitFlags {
+		log.Fatalf("the flag -fallback can't be used with -bitflags")
+	}
+	if *overridable && *bitFlags {
+		log.Fatalf("the flag -overridable can't be used with -bitflags")
+	}
+	if *docs && !*all && !*names {
+		log.Fatalf("the flag -docs needs -all or -names")
+	}
+	if len(*flagSep) == 0 {
+		log.Fatalf("the flag -flagsep can't be empty")
+	}
+	if *lookup != "map" && *lookup != "switch" && *lookup != "binary" {
+		log.Fatalf("unknown lookup %q", *lookup)
+	}
+	if len(*outputPath) == 0 && len(*outputPrefix) == 0 && len(strings.TrimSuffix(strings.ToLower(*outputSuffix), ".go")) == 0 {
+		log.Fatalf("the flags -prefix and -suffix can't both be empty unless -output is given")
+	}
+	if *noFormat && *fixImports {
+		log.Fatalf("the flag -fiximports formats the code, so it can't be used with -noformat")
+	}
+	if *deprecation != "accept" && *deprecation != "warn" && *deprecation != "reject" {
+		log.Fatalf("unknown handling of deprecated constants %q", *deprecation)
+	}
+	if *fileCase != "lower" && *fileCase != "snake" {
+		log.Fatalf("unknown case of file names %q", *fileCase)
+	}
+	if _, ok := transforms[*transform]; len(*transform) > 0 && !ok {
+		log.Fatalf("unknown transformation %q", *transform)
+	}
+	var licenseText string
+	if len(*license) > 0 {
+		var err error
+		licenseText, err = licenseHeader(*license)
+		if err != nil {
+			log.Fatalf("reading license header: %v", err)
+		}
+	}
+	tmpl := generatedTmpl
+	if len(*templateFile) > 0 {
+		var err error
+		tmpl, err = template.New(filepath.Base(*templateFile)).Funcs(funcs).ParseFiles(*templateFile)
+		if err != nil {
+			log.Fatalf("parsing template: %v", err)
+		}
+	}
 
-	// Only one directory at a time can be processed, and the default is ".".
-	dir := "."
-	if args := flag.Args(); len(args) == 1 {
-		dir = args[0]
-	} else if len(args) > 1 {
+	// Either a single directory or a list of files can be processed, and the
+	// default is ".".
+	args := flag.Args()
+	if len(args) == 0 {
+		args = []string{"."}
+	}
+	var files []string
+	for _, arg := range args {
+		if strings.HasSuffix(arg, ".go") {
+			file, err := filepath.Abs(arg)
+			if err != nil {
+				log.Fatalf("unable to determine absolute filepath for requested path %s: %v",
+					arg, err)
+			}
+			files = append(files, file)
+		}
+	}
+	if len(files) > 0 && len(files) < len(args) {
+		log.Fatalf("either a directory or Go files can be given, not both")
+	}
+	if len(files) == 0 && len(args) > 1 {
 		log.Fatalf("only one directory at a time")
 	}
+	dir := args[0]
+	if len(files) > 0 {
+		dir = commonDir(files)
+	}
 	dir, err := filepath.Abs(dir)
 	if err != nil {
 		log.Fatalf("unable to determine absolute filepath for requested path %s: %v",
 			dir, err)
 	}
 
-	pkg, err := parser.ParsePackage(dir)
+	conf := parser.Config{Tests: *tests, BestEffort: *bestEffort}
+	if *goFile {
+		conf.File = os.Getenv("GOFILE")
+		if len(conf.File) == 0 {
+			log.Fatalf("the flag -gofile needs $GOFILE set, as go generate does")
+		}
+	}
+	if len(*buildTags) > 0 {
+		conf.Tags = strings.Split(*buildTags, ",")
+	}
+	var pkg *parser.Package
+	if len(files) > 0 {
+		pkg, err = conf.ParseFiles(files)
+	} else {
+		pkg, err = conf.ParsePackage(dir)
+	}
 	if err != nil {
 		log.Fatalf("parsing package: %v", err)
 	}
+	if *verbose {
+		log.Printf("parsed package %s from %s", pkg.Name, strings.Join(pkg.Files(), ", "))
+		for _, err := range pkg.Errors() {
+			log.Printf("ignoring error: %v", err)
+		}
+	}
+	if *listOnly {
+		printEnums(pkg)
+		return
+	}
+	var typeList []string
+	if *allTypes {
+		typeList = pkg.EnumTypes()
+		if len(typeList) == 0 {
+			log.Fatalf("no integer or string types having constants found")
+		}
+	} else {
+		typeList, err = listTypes()
+		if err != nil {
+			log.Fatalf("listing types: %v", err)
+		}
+		typeList, err = unqualifyTypes(typeList, pkg.Name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		// Report all the types missing at once rather than one per run.
+		var missing []string
+		for _, typeName := range typeList {
+			if !pkg.HasType(typeName) {
+				missing = append(missing, typeName)
+			}
+		}
+		if len(missing) == 1 && !*keepGoing {
+			log.Fatalf("type %s not found in package %s", missing[0], pkg.Name)
+		} else if len(missing) > 1 && !*keepGoing {
+			log.Fatalf("types %s not found in package %s", strings.Join(missing, ", "), pkg.Name)
+		}
+	}
+	if len(*outputPath) > 0 && len(typeList) > 1 && !*single {
+		log.Fatalf("the flag -output can only be used with a single type or with -single")
+	}
+
+	// Collect the values of every type before generating anything. With
+	// -keepgoing the types failing are skipped, and the exit status tells
+	// about them at the end.
+	typesAndValues := make(map[string][]value)
+	enumTypes := make(map[string]enumType)
+	var collected []string
+	failed := false
+	for _, typeName := range typeList {
+		analyzed, typ, err := collectType(pkg, typeName)
+		if err != nil && !*keepGoing {
+			log.Fatal(err)
+		} else if err != nil {
+			log.Print(err)
+			failed = true
+			continue
+		}
+		typesAndValues[typeName] = analyzed
+		enumTypes[typeName] = typ
+		collected = append(collected, typeName)
+	}
+	if len(collected) == 0 {
+		os.Exit(1)
+	}
+	typeList = collected
+	fallbacks, err := fallbackConstants(typeList, typesAndValues)
+	if err != nil {
+		log.Fatalf("the flag -fallback: %v", err)
+	}
 
-	var analysis = struct {
-		Command        string
-		PackageName    string
-		TypesAndValues map[string][]string
-	}{
-		Command:        strings.Join(os.Args[1:], " "),
+	a := analysis{
+		Command:        command(),
 		PackageName:    pkg.Name,
-		TypesAndValues: make(map[string][]string),
+		License:        licenseText,
+		BuildTag:       *buildTag,
+		Types:          enumTypes,
+		Parse:          *parse || *mustParse,
+		MustParse:      *mustParse,
+		Stringer:       *stringer || *flagValue,
+		YAML:           *genYAML,
+		YAMLPackage:    *yamlPackage,
+		YAMLName:       yamlName(pkg),
+		MarshalUnknown: *onUnknown,
+		JSON:           *genJSON,
+		Text:           *genText,
+		XML:            *genXML,
+		SQL:            *genSQL,
+		FlagValue:      *flagValue,
+		Lookup:         *lookup,
+		ExportMaps:     *exportMaps,
+		Overridable:    *overridable,
+		IgnoreCase:     *ignoreCase,
+		AcceptInt:      *acceptInt || *marshalInt,
+		MarshalInt:     *marshalInt,
+		PtrMarshal:     *ptrMarshal,
+		YAMLTag:        *yamlTag,
+		EmptyZero:      *emptyZero,
+		Fallbacks:      fallbacks,
+		Valid:          *valid,
+		Validate:       *validate,
+		ErrType:        *errType,
+		All:            *all,
+		Names:          *names,
+		Docs:           *docs,
+		Ordinal:        *ordinal,
+		NameFunc:       *nameFunc,
+		Navigate:       *navigate,
+		BitFlags:       *bitFlags,
+		FlagString:     *flagString,
+		FlagSep:        *flagSep,
 	}
+	if *noCmd {
+		a.Command = ""
+	}
+	if len(*packageName) > 0 {
+		a.PackageName = *packageName
+	}
+	if len(*outputDir) > 0 {
+		dir = checkOutputDir(conf, dir, a.PackageName, typeList)
+	}
+	if len(*templateFile) > 0 {
+		a.WrapErrors = wrapsErrors(dir)
+	}
+	if *single {
+		a.TypesAndValues = typesAndValues
+		writeOutputs(dir, typeList[0], tmpl, a)
+	} else {
+		// Run generate for each type. Every file gets the values of its own
+		// type only, otherwise methods would be declared more than once.
+		for _, typeName := range typeList {
+			a.TypesAndValues = map[string][]value{typeName: typesAndValues[typeName]}
+			writeOutputs(dir, typeName, tmpl, a)
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
 
-	// Run generate for each type.
-	for _, typeName := range types {
+// printEnums prints the types -all-types would generate methods for and their
+// constants in declaration order, a line each as in
+//
+//	Pill	Aspirin	1
+//
+// the three fields being separated by tabs and strings being quoted.
+func printEnums(pkg *parser.Package) {
+	for _, typeName := range pkg.EnumTypes() {
 		values, err := pkg.ValuesOfType(typeName)
 		if err != nil {
 			log.Fatalf("finding values for type %v: %v", typeName, err)
 		}
-		analysis.TypesAndValues[typeName] = values
+		for _, v := range values {
+			fmt.Printf("%s\t%s\t%s\n", typeName, v.Name, v.Value.ExactString())
+		}
+	}
+}
+
+// listTypes returns the types given by -type and -typefile, each once. Empty
+// entries of -type, as left by stray commas of generated directives, are
+// skipped. The lines of the type file name a type each, unless they are blank
+// or comments starting with #.
+func listTypes() ([]string, error) {
+	var names []string
+	for _, name := range strings.Split(*typeNames, ",") {
+		if name = strings.TrimSpace(name); len(name) > 0 {
+			names = append(names, name)
+		}
+	}
+	if len(*typeFile) > 0 {
+		text, err := ioutil.ReadFile(*typeFile)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range strings.Split(string(text), "\n") {
+			if i := strings.Index(line, "#"); i >= 0 {
+				line = line[:i]
+			}
+			if line = strings.TrimSpace(line); len(line) > 0 {
+				names = append(names, line)
+			}
+		}
+	}
+	var typeList []string
+	seen := make(map[string]bool)
+	for _, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			typeList = append(typeList, name)
+		}
+	}
+	if len(typeList) == 0 && len(*typeFile) > 0 {
+		return nil, fmt.Errorf("neither -type nor %s lists any types", *typeFile)
+	}
+	if len(typeList) == 0 {
+		return nil, fmt.Errorf("-type=%s lists no types", *typeNames)
+	}
+	return typeList, nil
+}
+
+// fallbackConstants returns the constants given by -fallback by the types
+// they are of, making sure each is a constant of one of the types listed and
+// no type has two.
+func fallbackConstants(typeList []string, typesAndValues map[string][]value) (map[string]string, error) {
+	fallbacks := make(map[string]string)
+	if len(*fallback) == 0 {
+		return fallbacks, nil
+	}
+	for _, name := range strings.Split(*fallback, ",") {
+		found := false
+		for _, typeName := range typeList {
+			for _, v := range typesAndValues[typeName] {
+				if v.Name != name {
+					continue
+				}
+				if other, ok := fallbacks[typeName]; ok {
+					return nil, fmt.Errorf("both %s and %s are constants of type %s", other, name, typeName)
+				}
+				fallbacks[typeName] = name
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%s is not a constant of %s", name, strings.Join(typeList, " or "))
+		}
+	}
+	return fallbacks, nil
+}
+
+// unqualifyTypes strips the package qualifiers of the types listed, as in
+// painkiller.Pill, failing if they don't name the package parsed. Types listed
+// both with and without the qualifier are kept once.
+func unqualifyTypes(typeList []string, packageName string) ([]string, error) {
+	var names []string
+	seen := make(map[string]bool)
+	for _, name := range typeList {
+		if i := strings.LastIndex(name, "."); i >= 0 {
+			if qualifier := name[:i]; qualifier != packageName {
+				return nil, fmt.Errorf("type %s is qualified by package %s, but the package parsed is %s", name, qualifier, packageName)
+			}
+			name = name[i+1:]
+		}
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// collectType returns the constants of the named type as seen by the template
+// and the description of the type.
+func collectType(pkg *parser.Package, typeName string) ([]value, enumType, error) {
+	basic, err := pkg.BasicType(typeName)
+	if err != nil {
+		return nil, enumType{}, fmt.Errorf("finding type %v: %v", typeName, err)
+	}
+	values, err := pkg.ValuesOfType(typeName)
+	if err != nil {
+		return nil, enumType{}, fmt.Errorf("finding values for type %v: %v", typeName, err)
+	}
+	if *deprecation != "accept" {
+		var kept []parser.Value
+		for _, v := range values {
+			if !isDeprecated(v) {
+				kept = append(kept, v)
+			} else if *deprecation == "warn" {
+				log.Printf("warning: constant %s of type %s is deprecated", v.Name, typeName)
+			}
+		}
+		if *deprecation == "reject" {
+			if len(kept) == 0 {
+				return nil, enumType{}, fmt.Errorf("every constant of type %s is deprecated", typeName)
+			}
+			values = kept
+		}
+	}
+	analyzed, err := analyzeValues(values)
+	if err != nil {
+		return nil, enumType{}, fmt.Errorf("analyzing values of type %v: %v", typeName, err)
+	}
+	if *genExample && pkg.HasOwnMethod(typeName, "String") {
+		return nil, enumType{}, fmt.Errorf("the flag -example can't work out the output of the String method of %s", typeName)
+	}
+	if *marshalInt && basic.Info()&types.IsString != 0 {
+		return nil, enumType{}, fmt.Errorf("the flag -marshalint needs integer types, %s is a string type", typeName)
+	}
+	if *emptyZero && !hasZero(values) {
+		return nil, enumType{}, fmt.Errorf("the flag -emptyzero needs a constant of the zero value, %s has none", typeName)
+	}
+	if *bitFlags {
+		if basic.Info()&types.IsString != 0 {
+			return nil, enumType{}, fmt.Errorf("the flag -bitflags needs integer types, %s is a string type", typeName)
+		}
+		if err := checkBitFlags(values); err != nil {
+			return nil, enumType{}, fmt.Errorf("analyzing values of type %v: %v", typeName, err)
+		}
+		for _, v := range analyzed {
+			if *flagString && strings.Contains(v.Str, *flagSep) {
+				return nil, enumType{}, fmt.Errorf("analyzing values of type %v: %s is marshaled to %q containing the separator %q",
+					typeName, v.Name, v.Str, *flagSep)
+			}
+		}
+	}
+	if *verify && pkg.HasOwnMethod(typeName, "String") {
+		return nil, enumType{}, fmt.Errorf("the flag -verify can't work out the output of the String method of %s", typeName)
+	} else if *verify {
+		if err := verifyRoundTrip(values, analyzed); err != nil {
+			return nil, enumType{}, fmt.Errorf("verifying values of type %v: %v", typeName, err)
+		}
+	}
+	if *verbose {
+		var found []string
+		for i, v := range values {
+			found = append(found, fmt.Sprintf("%s = %s as %q", v.Name, v.Value, analyzed[i].Str))
+		}
+		log.Printf("found constants of type %s: %s", typeName, strings.Join(found, ", "))
+	}
+	var sorted []value
+	for _, v := range analyzed {
+		if !v.Repeat {
+			sorted = append(sorted, v)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Str < sorted[j].Str })
+	return analyzed, enumType{
+		Kind:     basic.Name(),
+		String:   basic.Info()&types.IsString != 0,
+		Unsigned: basic.Info()&types.IsUnsigned != 0,
+		Sorted:   sorted,
+	}, nil
+}
+
+// yamlName returns the name the generated files import the YAML package
+// under. The files of the package importing it already decide it, so the
+// generated ones agree with them. Otherwise it's yaml, or goyaml, goyaml2 and
+// so on if the package declares yaml itself.
+func yamlName(pkg *parser.Package) string {
+	path := "gopkg.in/yaml.v3"
+	switch *yamlPackage {
+	case "v2":
+		path = "gopkg.in/yaml.v2"
+	case "sigs":
+		path = "sigs.k8s.io/yaml"
+	}
+	if name, ok := pkg.ImportName(path); ok {
+		return name
+	}
+	name := "yaml"
+	for i := 1; pkg.HasName(name); i++ {
+		name = "goyaml"
+		if i > 1 {
+			name += strconv.Itoa(i)
+		}
+	}
+	return name
+}
+
+// checkOutputDir returns the absolute path of the directory given by -dir,
+// making sure the package there declares the types, as methods can only be
+// declared in the package of their type.
+func checkOutputDir(conf parser.Config, dir, packageName string, typeList []string) string {
+	target, err := filepath.Abs(*outputDir)
+	if err != nil {
+		log.Fatalf("unable to determine absolute filepath for requested path %s: %v",
+			*outputDir, err)
+	}
+	if target == dir {
+		return target
+	}
+	conf.File = ""
+	pkg, err := conf.ParsePackage(target)
+	if err != nil {
+		log.Fatalf("parsing package in output directory: %v", err)
+	}
+	if pkg.Name != packageName {
+		log.Fatalf("output directory %s holds package %s, not %s", target, pkg.Name, packageName)
+	}
+	for _, typeName := range typeList {
+		if _, err := pkg.BasicType(typeName); err != nil {
+			log.Fatalf("output directory %s doesn't declare type %s: methods can only be declared in the package of their type",
+				target, typeName)
+		}
+	}
+	return target
+}
+
+// commonDir returns the deepest directory holding all the files.
+func commonDir(files []string) string {
+	dir := filepath.Dir(files[0])
+	for _, file := range files[1:] {
+		for !strings.HasPrefix(file, dir+string(filepath.Separator)) {
+			parent := filepath.Dir(dir)
+			if parent == dir {
+				break
+			}
+			dir = parent
+		}
+	}
+	return dir
+}
+
+// writeOutputs generates the code for the named type with tmpl and writes it,
+// along with the tests if -gentests is given, the examples if -example is and
+// the schemas of the types if -schema is.
+func writeOutputs(dir, typeName string, tmpl *template.Template, a analysis) {
+	path := outputFile(dir, typeName)
+	// The imports are resolved as if the code were written in dir, which
+	// holds the package with -output=- too.
+	goFile := path
+	if path == "-" {
+		goFile = filepath.Join(dir, strings.ToLower(typeName)+".go")
+	}
+	writeOutput(path, generate(tmpl, a, goFile))
+	if *genTests {
+		testPath := strings.TrimSuffix(path, ".go") + "_test.go"
+		writeOutput(testPath, generate(generatedTestTmpl, a, strings.TrimSuffix(goFile, ".go")+"_test.go"))
+	}
+	if *genExample {
+		examplePath := strings.TrimSuffix(path, ".go") + "_example_test.go"
+		writeOutput(examplePath, generate(generatedExampleTmpl, a, strings.TrimSuffix(goFile, ".go")+"_example_test.go"))
+	}
+	if *genSchema {
+		var names []string
+		for name := range a.TypesAndValues {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			writeOutput(schemaFile(path, name), generateSchema(name, a.TypesAndValues[name]))
+		}
+	}
+}
+
+// licenseHeader reads the header file at path and turns its lines into line
+// comments. Headers written as line comments already are kept as they are.
+func licenseHeader(path string) (string, error) {
+	text, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	lines := strings.Split(strings.TrimRight(string(text), "\r\n"), "\n")
+	commented := true
+	for _, line := range lines {
+		if len(strings.TrimSpace(line)) > 0 && !strings.HasPrefix(line, "//") {
+			commented = false
+		}
+	}
+	if commented {
+		return strings.Join(lines, "\n"), nil
+	}
+	for i, line := range lines {
+		line = strings.TrimRight(line, " \t\r")
+		if len(line) == 0 {
+			lines[i] = "//"
+		} else {
+			lines[i] = "// " + line
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// yamlTagRE matches the tags -yamltag accepts: local tags like !pill and
+// global ones like !!str or !<tag:example.com,2020:pill>.
+var yamlTagRE = regexp.MustCompile(`^!(!?[\w.\-/:]+|<[^<>\s]+>)$`)
 
-		var buf bytes.Buffer
-		if err := generatedTmpl.Execute(&buf, analysis); err != nil {
-			log.Fatalf("generating code: %v", err)
+// goVersionRE matches the Go versions -go accepts and go directives give,
+// release candidates like 1.21rc1 included, holding the minor version.
+var goVersionRE = regexp.MustCompile(`^1\.(\d+)(\.\d+|(rc|beta)\d+)?$`)
+
+// wrapsErrors reports whether the Go version the generated code targets has
+// fmt.Errorf wrapping errors with %w, which came with Go 1.13. The version is
+// given by -go, or else by the go directive of the module holding dir. The
+// code targets the current Go without either, and the oldest one if the go
+// directive can't be made sense of, as it then compiles anyway. Only custom
+// templates care.
+func wrapsErrors(dir string) bool {
+	version := *goVersion
+	if len(version) == 0 {
+		version = moduleGoVersion(dir)
+	}
+	if len(version) == 0 {
+		return true
+	}
+	m := goVersionRE.FindStringSubmatch(version)
+	if m == nil {
+		return false
+	}
+	minor, err := strconv.Atoi(m[1])
+	return err == nil && minor >= 13
+}
+
+// moduleGoVersion returns the version given by the go directive of the
+// go.mod file of dir or of its closest parent having one, if any.
+func moduleGoVersion(dir string) string {
+	for {
+		text, err := ioutil.ReadFile(filepath.Join(dir, "go.mod"))
+		if err == nil {
+			for _, line := range strings.Split(string(text), "\n") {
+				if fields := strings.Fields(line); len(fields) == 2 && fields[0] == "go" {
+					return fields[1]
+				}
+			}
+			return ""
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// buildTagRE matches the build tags -buildtag accepts. A single tag reads the
+// same in the //go:build and the // +build syntax.
+var buildTagRE = regexp.MustCompile(`^!?[\pL\pN_.]+$`)
+
+// command returns the arguments yamlenums is run with, less the ones not
+// affecting the generated code, so that running with -dryrun or -force
+// generates the same code.
+func command() string {
+	var args []string
+	for _, arg := range os.Args[1:] {
+		name := strings.SplitN(strings.TrimLeft(arg, "-"), "=", 2)[0]
+		if strings.HasPrefix(arg, "-") && (name == "dryrun" || name == "force") {
+			continue
 		}
+		args = append(args, arg)
+	}
+	return strings.Join(args, " ")
+}
+
+// analysis is the data the template is executed with. Templates given by
+// -template get it too, so its fields are documented in the README.
+type analysis struct {
+	// Command is the arguments yamlenums was run with, empty with -nocmd.
+	Command string
+	// PackageName is the name of the package the code is generated for.
+	PackageName string
+	// License is the header given by -license as line comments, if any.
+	License string
+	// BuildTag is the build tag constraining the generated files, if any.
+	BuildTag string
+	// WrapErrors is set if the Go version targeted has fmt.Errorf wrapping
+	// errors with %w.
+	WrapErrors bool
+	// TypesAndValues maps the names of the types to generate the code for
+	// to their constants in declaration order. Templates range over maps in
+	// key order, so the generated code doesn't change from run to run.
+	TypesAndValues map[string][]value
+	// Types describes the types listed in TypesAndValues.
+	Types map[string]enumType
 
-		src, err := format.Source(buf.Bytes())
+	// Parse enables generation of ParseT functions.
+	Parse bool
+	// MustParse enables generation of MustParseT functions. It requires
+	// Parse.
+	MustParse bool
+	// Stringer enables generation of String methods.
+	Stringer bool
+	// YAML enables generation of MarshalYAML and UnmarshalYAML methods.
+	YAML bool
+	// YAMLPackage is the go-yaml major version, v2 or v3, the YAML methods
+	// are generated for, both for methods working with either, or sigs to
+	// generate the JSON methods sigs.k8s.io/yaml relies on instead.
+	YAMLPackage string
+	// YAMLName is the name the generated files import the YAML package
+	// under.
+	YAMLName string
+	// MarshalUnknown is what MarshalYAML does with values of integer types
+	// having no name: error or int.
+	MarshalUnknown string
+	// JSON enables generation of MarshalJSON and UnmarshalJSON methods.
+	JSON bool
+	// Text enables generation of MarshalText and UnmarshalText methods.
+	Text bool
+	// XML enables generation of MarshalXML and UnmarshalXML methods.
+	XML bool
+	// SQL enables generation of Value and Scan methods.
+	SQL bool
+	// FlagValue enables generation of Set methods. String methods are
+	// generated too, as enabled by Stringer.
+	FlagValue bool
+	// Lookup is how the constants named by strings are found: map, switch
+	// or binary.
+	Lookup string
+	// ExportMaps makes the maps between the strings and the values exported.
+	ExportMaps bool
+	// Overridable enables generation of the maps overriding the strings of
+	// the constants at run time.
+	Overridable bool
+	// IgnoreCase makes unmarshaling match names case-insensitively.
+	IgnoreCase bool
+	// AcceptInt makes UnmarshalYAML accept integer values of the constants.
+	AcceptInt bool
+	// MarshalInt makes MarshalYAML produce integer values of the constants.
+	MarshalInt bool
+	// PtrMarshal makes MarshalYAML methods have pointer receivers.
+	PtrMarshal bool
+	// YAMLTag is the tag of the scalars MarshalYAML produces, if any.
+	YAMLTag string
+	// EmptyZero makes UnmarshalYAML and UnmarshalXML decode empty strings to
+	// the zero value.
+	EmptyZero bool
+	// Fallbacks maps the name of every type having a constant given by
+	// -fallback to the constant, which unknown strings unmarshal to.
+	Fallbacks map[string]string
+	// Valid enables generation of IsValid methods.
+	Valid bool
+	// Validate enables generation of Validate methods.
+	Validate bool
+	// ErrType enables generation of InvalidTError types.
+	ErrType bool
+	// All enables generation of AllT functions.
+	All bool
+	// Names enables generation of TNames functions.
+	Names bool
+	// Docs enables carrying the doc comments of the constants into the
+	// lists of AllT and TNames functions.
+	Docs bool
+	// Ordinal enables generation of Ordinal methods and TFromOrdinal
+	// functions.
+	Ordinal bool
+	// NameFunc enables generation of TName functions.
+	NameFunc bool
+	// Navigate enables generation of Next and Prev methods.
+	Navigate bool
+	// BitFlags makes the YAML methods marshal combinations of the constants
+	// as sequences of their names.
+	BitFlags bool
+	// FlagString makes the YAML methods marshal combinations of the
+	// constants as single strings instead, the names joined by FlagSep.
+	FlagString bool
+	FlagSep    string
+}
+
+// enumType is a type as seen by the template.
+type enumType struct {
+	// Kind is the name of the underlying type, such as int8 or string.
+	Kind string
+	// String is set for string types.
+	String bool
+	// Unsigned is set for unsigned integer types.
+	Unsigned bool
+	// Sorted lists the values of the type sorted by their strings.
+	Sorted []value
+}
+
+// value is a constant of a type as seen by the template.
+type value struct {
+	// Name is the name of the constant.
+	Name string
+	// Str is the string the constant is marshaled to.
+	Str string
+	// Alias is set if the name of another constant with the same value is
+	// used for the value, which is the first one declared unless deprecated.
+	Alias bool
+	// Repeat is set for aliases marshaled to the string of a constant
+	// declared before, so the string is listed once.
+	Repeat bool
+	// Ordinal is the position of the value among the distinct values of the
+	// type in declaration order, shared by aliases.
+	Ordinal int
+	// Zero is set for constants of integer types having the value 0.
+	Zero bool
+	// Deprecated is set for constants having Deprecated: doc comments.
+	Deprecated bool
+	// Doc is the text of the doc comment of the constant, if any.
+	Doc string
+}
+
+// analyzeValues determines the strings of the constants, which must be
+// distinct. It also marks every constant having the same value as a constant
+// declared before it as an alias, so the first name is used for the value,
+// unless it's deprecated and a later one isn't. Depending on -dupes aliases
+// are warned about or rejected.
+func analyzeValues(values []parser.Value) ([]value, error) {
+	var result []value
+	firsts := make(map[string]string)
+	// names holds the index of the first constant marshaled to every string.
+	names := make(map[string]int)
+	// primaries holds the index of the constant whose name is used for every
+	// value.
+	primaries := make(map[string]int)
+	for _, v := range values {
+		str := v.Name
+		if v.Value.Kind() == constant.String {
+			str = constant.StringVal(v.Value)
+		}
+		str, err := trimName(str)
 		if err != nil {
-			// Should never happen, but can arise when developing this code.
-			// The user can compile the output to see the error.
-			log.Printf("warning: internal error: invalid Go generated: %s", err)
+			return nil, err
+		}
+		if len(*transform) > 0 {
+			str = transforms[*transform](str)
+		}
+		if *lineComment && len(v.Comment) > 0 {
+			str = v.Comment
+		}
+		if len(v.Override) > 0 {
+			str = v.Override
+		}
+		key := v.Value.ExactString()
+		// Constants of string types aliasing others have the same string,
+		// which then stands for both.
+		i, repeat := names[str]
+		if repeat && values[i].Value.ExactString() != key {
+			return nil, fmt.Errorf("both %s and %s are marshaled to %q", values[i].Name, v.Name, str)
+		}
+		if !repeat {
+			names[str] = len(result)
+		}
+		if *ignoreCase {
+			// Strings differing only in case name the same constant to
+			// the generated lookup, so they may only name one value.
+			for i, r := range result {
+				if strings.EqualFold(r.Str, str) && values[i].Value.ExactString() != key {
+					return nil, fmt.Errorf("%s and %s are marshaled to %q and %q, which are the same ignoring case", r.Name, v.Name, r.Str, str)
+				}
+			}
+		}
+		first, alias := firsts[key]
+		if alias && *dupes == "warn" {
+			log.Printf("warning: %s has the same value as %s", v.Name, first)
+		} else if alias && *dupes == "error" {
+			return nil, fmt.Errorf("%s has the same value as %s", v.Name, first)
+		}
+		deprecated := isDeprecated(v)
+		if !alias {
+			firsts[key] = v.Name
+			primaries[key] = len(result)
+		} else if i := primaries[key]; result[i].Deprecated && !deprecated {
+			result[i].Alias = true
+			primaries[key] = len(result)
+		}
+		result = append(result, value{
+			Name:       v.Name,
+			Str:        str,
+			Alias:      primaries[key] != len(result),
+			Repeat:     repeat,
+			Zero:       v.Value.Kind() == constant.Int && constant.Sign(v.Value) == 0,
+			Deprecated: deprecated,
+			Doc:        v.Doc,
+		})
+	}
+	// The ordinals follow the order of the names used, which changes when a
+	// later name takes the place of a deprecated one.
+	ordinals := make(map[string]int)
+	for i, v := range values {
+		if !result[i].Alias {
+			ordinals[v.Value.ExactString()] = len(ordinals)
+		}
+	}
+	for i, v := range values {
+		result[i].Ordinal = ordinals[v.Value.ExactString()]
+	}
+	return result, nil
+}
+
+// isDeprecated reports whether the doc comment of v has a paragraph starting
+// with "Deprecated:", as the go tool recognizes.
+func isDeprecated(v parser.Value) bool {
+	for _, paragraph := range strings.Split(v.Doc, "\n\n") {
+		if strings.HasPrefix(paragraph, "Deprecated:") {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyRoundTrip makes sure every constant unmarshals back to its value
+// from what MarshalYAML produces for it. The maps from the strings to the
+// values and back are built as the generated code builds them, and what's
+// marshaled is taken apart and looked up the way the generated UnmarshalYAML
+// does, with -ignorecase, -acceptint, -marshalint, -emptyzero, -fallback,
+// -bitflags and -flagstring taken into account. The lookups of -lookup all
+// find the same values, so the maps stand for them. The errors name the
+// constants whose values are lost.
+func verifyRoundTrip(values []parser.Value, analyzed []value) error {
+	nameToValue := make(map[string]constant.Value)
+	valueToName := make(map[string]string)
+	var fallbackValue constant.Value
+	for i, v := range analyzed {
+		nameToValue[v.Str] = values[i].Value
+		if !v.Alias {
+			valueToName[values[i].Value.ExactString()] = v.Str
+		}
+		for _, name := range strings.Split(*fallback, ",") {
+			if name == v.Name {
+				fallbackValue = values[i].Value
+			}
+		}
+	}
+	// parse looks s up as the generated parse function does.
+	parse := func(s string) (constant.Value, bool) {
+		if value, ok := nameToValue[s]; ok {
+			return value, true
+		}
+		if *ignoreCase {
+			for name, value := range nameToValue {
+				if strings.EqualFold(name, s) {
+					return value, true
+				}
+			}
+		}
+		return nil, false
+	}
+	for _, v := range values {
+		str := valueToName[v.Value.ExactString()]
+		shown := strconv.Quote(str)
+		var got constant.Value
+		ok := true
+		switch {
+		case *bitFlags:
+			// The bits of no constant are zero, which marshals to no names,
+			// and the others are single bits marshaling to their names.
+			names := []string{str}
+			if constant.Sign(v.Value) == 0 {
+				names = nil
+			} else if *flagString {
+				names = strings.Split(str, *flagSep)
+			}
+			if !*flagString {
+				shown = fmt.Sprintf("%q", names)
+			} else if len(names) == 0 {
+				shown = `""`
+			}
+			got = constant.MakeInt64(0)
+			for _, name := range names {
+				if *flagString {
+					name = strings.TrimSpace(name)
+				}
+				bit, found := parse(name)
+				if !found {
+					ok = false
+					break
+				}
+				got = constant.BinaryOp(got, token.OR, bit)
+			}
+		default:
+			if *marshalInt {
+				str = v.Value.ExactString()
+				shown = str
+			}
+			got, ok = parse(str)
+			// Integers are taken for the values they name, if any, and
+			// strings naming nothing for the fallback.
+			i := constant.MakeFromLiteral(str, token.INT, 0)
+			_, named := valueToName[i.ExactString()]
+			switch {
+			case *emptyZero && str == "" && v.Value.Kind() == constant.String:
+				got, ok = constant.MakeString(""), true
+			case *emptyZero && str == "":
+				got, ok = constant.MakeInt64(0), true
+			case !ok && (*acceptInt || *marshalInt) && v.Value.Kind() == constant.Int && i.Kind() == constant.Int && named:
+				got, ok = i, true
+			case !ok && fallbackValue != nil:
+				got, ok = fallbackValue, true
+			}
+		}
+		if !ok {
+			return fmt.Errorf("%s is marshaled to %s, which unmarshals to no value", v.Name, shown)
+		}
+		if !constant.Compare(got, token.EQL, v.Value) {
+			return fmt.Errorf("%s = %s is marshaled to %s, which unmarshals to %s", v.Name, v.Value, shown, got)
+		}
+	}
+	return nil
+}
+
+// checkBitFlags makes sure every constant is a power of two or zero, so the
+// constants can be combined as bits.
+func checkBitFlags(values []parser.Value) error {
+	one := constant.MakeInt64(1)
+	for _, v := range values {
+		if constant.Sign(v.Value) == 0 {
+			continue
+		}
+		below := constant.BinaryOp(v.Value, token.SUB, one)
+		if constant.Sign(v.Value) < 0 || constant.Sign(constant.BinaryOp(v.Value, token.AND, below)) != 0 {
+			return fmt.Errorf("%s = %s is not a power of two", v.Name, v.Value)
+		}
+	}
+	return nil
+}
+
+// hasZero reports whether one of the constants has the zero value of its
+// type, 0 or the empty string, which -emptyzero unmarshals empty scalars to.
+func hasZero(values []parser.Value) bool {
+	for _, v := range values {
+		switch v.Value.Kind() {
+		case constant.Int:
+			if constant.Sign(v.Value) == 0 {
+				return true
+			}
+		case constant.String:
+			if constant.StringVal(v.Value) == "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// trimName trims the first matching prefix given by -trimprefix and the first
+// matching suffix given by -trimsuffix from the name or the string value of a
+// constant.
+func trimName(name string) (string, error) {
+	str := name
+	if len(*trimPrefix) > 0 {
+		for _, prefix := range strings.Split(*trimPrefix, ",") {
+			if strings.HasPrefix(str, prefix) {
+				str = str[len(prefix):]
+				break
+			}
+		}
+	}
+	if len(*trimSuffix) > 0 {
+		for _, suffix := range strings.Split(*trimSuffix, ",") {
+			if strings.HasSuffix(str, suffix) {
+				str = str[:len(str)-len(suffix)]
+				break
+			}
+		}
+	}
+	if len(str) == 0 && len(name) > 0 {
+		return "", fmt.Errorf("trimming leaves nothing of %s", name)
+	}
+	return str, nil
+}
+
+// generate executes the template and formats the resulting source unless
+// -noformat is given. With -fiximports the imports are fixed too, as for the
+// Go file named filename.
+func generate(tmpl *template.Template, a analysis, filename string) []byte {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, a); err != nil {
+		log.Fatalf("generating code: %v", err)
+	}
+	if *noFormat {
+		return buf.Bytes()
+	}
+	if *fixImports {
+		src, err := imports.Process(filename, buf.Bytes(), nil)
+		if err != nil {
+			log.Printf("warning: fixing imports: %s", err)
 			log.Printf("warning: compile the package to analyze the error")
-			src = buf.Bytes()
+			return buf.Bytes()
 		}
+		return src
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		// Should never happen, but can arise when developing this code.
+		// The user can compile the output to see the error.
+		log.Printf("warning: internal error: invalid Go generated: %s", err)
+		log.Printf("warning: compile the package to analyze the error")
+		src = buf.Bytes()
+	}
+	return src
+}
 
-		output := strings.ToLower(*outputPrefix + typeName +
-			*outputSuffix + ".go")
-		outputPath := filepath.Join(dir, output)
-		if err := ioutil.WriteFile(outputPath, src, 0644); err != nil {
+// outputFile returns the path the code generated for the named type is
+// written to: either the one given by -output or a file derived from the type
+// name in dir.
+func outputFile(dir, typeName string) string {
+	if len(*outputPath) > 0 {
+		return *outputPath
+	}
+	if *fileCase == "snake" {
+		typeName = transforms["snake"](typeName)
+	}
+	// The prefix and the suffix are lower-cased along with the type name, as
+	// they always were, so existing go:generate lines keep their files, and a
+	// .go ending the suffix isn't doubled.
+	output := strings.TrimSuffix(strings.ToLower(*outputPrefix+typeName+*outputSuffix), ".go") + ".go"
+	return filepath.Join(dir, output)
+}
+
+// writeOutput writes src to path, - standing for the standard output. Files
+// already holding src are left alone unless -force is given. With -dryrun it
+// only logs what it would write.
+func writeOutput(path string, src []byte) {
+	if path == "-" {
+		if _, err := os.Stdout.Write(src); err != nil {
+			log.Fatalf("writing output: %s", err)
+		}
+		return
+	}
+	if !*force {
+		// Leaving up to date files alone keeps their modification times, so
+		// builds depending on them aren't redone.
+		if old, err := ioutil.ReadFile(path); err == nil && bytes.Equal(old, src) {
+			if info, err := os.Stat(path); err == nil && len(*perm) > 0 && info.Mode().Perm() != fileMode && !*dryRun {
+				if err := os.Chmod(path, fileMode); err != nil {
+					log.Fatalf("writing output: %s", err)
+				}
+			}
+			return
+		}
+	}
+	if *dryRun {
+		log.Printf("would write %s (%d bytes)", path, len(src))
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		log.Fatalf("creating output directory: %v", err)
+	}
+	// Read-only files, such as those of -perm=0444, can't be opened for
+	// writing, so they are made writable for the time being.
+	mode, readOnly := fileMode, false
+	if info, err := os.Stat(path); err == nil && info.Mode().Perm()&0200 == 0 {
+		if len(*perm) == 0 {
+			mode = info.Mode().Perm()
+		}
+		readOnly = true
+		if err := os.Chmod(path, info.Mode().Perm()|0200); err != nil {
 			log.Fatalf("writing output: %s", err)
 		}
 	}
+	if err := ioutil.WriteFile(path, src, fileMode); err != nil {
+		log.Fatalf("writing output: %s", err)
+	}
+	// New files are subject to the umask and existing ones keep their mode,
+	// which only -perm overrides.
+	if len(*perm) > 0 || readOnly {
+		if err := os.Chmod(path, mode); err != nil {
+			log.Fatalf("writing output: %s", err)
+		}
+	}
+}
+
+// parsePerm parses the octal permissions given by -perm. The files must stay
+// readable by their owner, or go build couldn't read them.
+func parsePerm(s string) (os.FileMode, error) {
+	mode, err := strconv.ParseUint(s, 8, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not an octal number", s)
+	}
+	if mode > 0777 {
+		return 0, fmt.Errorf("%q has bits beyond the permissions 0777", s)
+	}
+	if mode&0400 == 0 {
+		return 0, fmt.Errorf("%q makes the files unreadable by their owner", s)
+	}
+	return os.FileMode(mode), nil
 }
@@ -0,0 +1,3190 @@
+// Copyright 2020 igrmk. All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to writing, software distributed
+// under the License is distributed on a "AS IS" BASIS, WITHOUT WARRANTIES OR
+// CONDITIONS OF ANY KIND, either express or implied.
+//
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlenums is the path to the binary built for the tests.
+var yamlenums string
+
+func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "yamlenums")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	yamlenums = filepath.Join(dir, "yamlenums")
+	if out, err := exec.Command("go", "build", "-o", yamlenums, ".").CombinedOutput(); err != nil {
+		fmt.Fprintf(os.Stderr, "building yamlenums: %v\n%s", err, out)
+		os.Exit(1)
+	}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func must(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+const goMod = `module example.com/painkiller
+
+go 1.14
+
+require gopkg.in/yaml.v3 v3.0.0-20200506231410-2ff61e1afc86
+`
+
+var pillCode = `
+package painkiller
+
+type Pill int
+
+const (
+	Placebo Pill = iota
+	Aspirin
+	Ibuprofen
+	Paracetamol
+	Acetaminophen = Paracetamol
+)
+`
+
+var colorCode = `
+package painkiller
+
+type Color int
+
+const (
+	Red Color = iota
+	Green
+	Blue
+)
+`
+
+// writePackage creates a module in a temporary directory holding the given
+// files, which may be in subdirectories, and returns the directory.
+func writePackage(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "painkiller")
+	must(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	goSum, err := ioutil.ReadFile("go.sum")
+	must(t, err)
+	must(t, ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte(goMod), 0644))
+	must(t, ioutil.WriteFile(filepath.Join(dir, "go.sum"), goSum, 0644))
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		must(t, os.MkdirAll(filepath.Dir(path), 0755))
+		must(t, ioutil.WriteFile(path, []byte(src), 0644))
+	}
+	return dir
+}
+
+// runYamlenums runs yamlenums with the given arguments in dir and returns its
+// output.
+func runYamlenums(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command(yamlenums, args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("running yamlenums %v: %v\n%s", args, err, out)
+	}
+	return string(out)
+}
+
+// runYamlenumsFail runs yamlenums with the given arguments in dir expecting
+// it to fail and returns its output.
+func runYamlenumsFail(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command(yamlenums, args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		t.Fatalf("running yamlenums %v succeeded", args)
+	}
+	return string(out)
+}
+
+// goTest writes the test source to dir and runs go test there, so the
+// generated code is compiled and exercised.
+func goTest(t *testing.T, dir, src string) {
+	t.Helper()
+	must(t, ioutil.WriteFile(filepath.Join(dir, "yamlenums_test.go"), []byte(src), 0644))
+	cmd := exec.Command("go", "test", ".")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("go test: %v\n%s", err, out)
+	}
+}
+
+// methods returns the methods declared in the file as sorted
+// "Receiver.Method" strings.
+func methods(t *testing.T, path string) []string {
+	t.Helper()
+	f, err := parser.ParseFile(token.NewFileSet(), path, nil, 0)
+	must(t, err)
+	var ms []string
+	for _, decl := range f.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv == nil {
+			continue
+		}
+		recv := fn.Recv.List[0].Type
+		if star, ok := recv.(*ast.StarExpr); ok {
+			recv = star.X
+		}
+		ms = append(ms, recv.(*ast.Ident).Name+"."+fn.Name.Name)
+	}
+	sort.Strings(ms)
+	return ms
+}
+
+func TestMethodsOfOwnTypeOnly(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": pillCode, "color.go": colorCode})
+	runYamlenums(t, dir, "-type=Pill,Color")
+
+	for file, typeName := range map[string]string{
+		"pill_yamlenums.go":  "Pill",
+		"color_yamlenums.go": "Color",
+	} {
+		want := []string{typeName + ".MarshalYAML", typeName + ".UnmarshalYAML"}
+		got := methods(t, filepath.Join(dir, file))
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("%s declares %v, want %v", file, got, want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": pillCode})
+	runYamlenums(t, dir, "-type=Pill")
+	goTest(t, dir, `
+package painkiller
+
+import "testing"
+
+func TestParsePill(t *testing.T) {
+	if v, err := ParsePill("Aspirin"); err != nil || v != Aspirin {
+		t.Errorf("ParsePill(%q) = %v, %v", "Aspirin", v, err)
+	}
+	if _, err := ParsePill("Asprin"); err == nil {
+		t.Errorf("ParsePill(%q) succeeded", "Asprin")
+	}
+}
+`)
+}
+
+func TestStringer(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": pillCode})
+	runYamlenums(t, dir, "-type=Pill", "-stringer")
+	goTest(t, dir, `
+package painkiller
+
+import "testing"
+
+func TestString(t *testing.T) {
+	if s := Aspirin.String(); s != "Aspirin" {
+		t.Errorf("Aspirin.String() = %q", s)
+	}
+	if s := Pill(12).String(); s != "Pill(12)" {
+		t.Errorf("Pill(12).String() = %q", s)
+	}
+}
+`)
+}
+
+func TestJSON(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": pillCode})
+	runYamlenums(t, dir, "-type=Pill", "-json", "-yaml=false")
+	want := []string{"Pill.MarshalJSON", "Pill.UnmarshalJSON"}
+	if got := methods(t, filepath.Join(dir, "pill_yamlenums.go")); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("pill_yamlenums.go declares %v, want %v", got, want)
+	}
+	goTest(t, dir, `
+package painkiller
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestJSON(t *testing.T) {
+	b, err := json.Marshal(Aspirin)
+	if err != nil || string(b) != "\"Aspirin\"" {
+		t.Errorf("json.Marshal(Aspirin) = %s, %v", b, err)
+	}
+	var p Pill
+	if err := json.Unmarshal([]byte("\"Ibuprofen\""), &p); err != nil || p != Ibuprofen {
+		t.Errorf("unmarshaled %v, %v", p, err)
+	}
+	for _, s := range []string{"1", "\"Asprin\"", "{"} {
+		if err := json.Unmarshal([]byte(s), &p); err == nil {
+			t.Errorf("unmarshaling %s succeeded", s)
+		}
+	}
+}
+`)
+}
+
+func TestText(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": pillCode})
+	runYamlenums(t, dir, "-type=Pill", "-text", "-parse=false")
+	goTest(t, dir, `
+package painkiller
+
+import "testing"
+
+func TestText(t *testing.T) {
+	b, err := Aspirin.MarshalText()
+	if err != nil || string(b) != "Aspirin" {
+		t.Errorf("Aspirin.MarshalText() = %s, %v", b, err)
+	}
+	var p Pill
+	if err := p.UnmarshalText([]byte("Ibuprofen")); err != nil || p != Ibuprofen {
+		t.Errorf("unmarshaled %v, %v", p, err)
+	}
+	if err := p.UnmarshalText([]byte("Asprin")); err == nil {
+		t.Error("unmarshaling Asprin succeeded")
+	}
+}
+`)
+}
+
+func TestMapKeys(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": pillCode})
+	runYamlenums(t, dir, "-type=Pill", "-json", "-text")
+	goTest(t, dir, `
+package painkiller
+
+import (
+	"encoding/json"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestYAMLMapKeys(t *testing.T) {
+	b, err := yaml.Marshal(map[Pill]int{Aspirin: 1})
+	if err != nil || string(b) != "Aspirin: 1\n" {
+		t.Errorf("the map is marshaled to %q, %v", b, err)
+	}
+	var m map[Pill]int
+	if err := yaml.Unmarshal([]byte("Ibuprofen: 2\n"), &m); err != nil || len(m) != 1 || m[Ibuprofen] != 2 {
+		t.Errorf("the map is unmarshaled as %v, %v", m, err)
+	}
+}
+
+// encoding/json marshals map keys with the methods of -text only.
+func TestJSONMapKeys(t *testing.T) {
+	b, err := json.Marshal(map[Pill]int{Aspirin: 1})
+	if err != nil || string(b) != "{\"Aspirin\":1}" {
+		t.Errorf("the map is marshaled to %s, %v", b, err)
+	}
+	var m map[Pill]int
+	if err := json.Unmarshal([]byte("{\"Ibuprofen\":2}"), &m); err != nil || len(m) != 1 || m[Ibuprofen] != 2 {
+		t.Errorf("the map is unmarshaled as %v, %v", m, err)
+	}
+}
+`)
+}
+
+var deprecatedPillCode = `
+package painkiller
+
+type Pill int
+
+const (
+	Placebo Pill = iota
+	// Deprecated: Use Acetaminophen.
+	Paracetamol
+	Aspirin
+	Acetaminophen = Paracetamol
+)
+`
+
+func TestDeprecated(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": deprecatedPillCode})
+	runYamlenums(t, dir, "-type=Pill", "-stringer", "-all", "-ordinal")
+	goTest(t, dir, `
+package painkiller
+
+import (
+	"fmt"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestDeprecated(t *testing.T) {
+	if b, err := yaml.Marshal(Paracetamol); err != nil || string(b) != "Acetaminophen\n" {
+		t.Errorf("marshaling Paracetamol gives %q, %v", b, err)
+	}
+	var p Pill
+	if err := yaml.Unmarshal([]byte("Paracetamol"), &p); err != nil || p != Acetaminophen {
+		t.Errorf("unmarshaling Paracetamol gives %v, %v", p, err)
+	}
+	if s := Paracetamol.String(); s != "Acetaminophen" {
+		t.Errorf("Paracetamol.String() = %q", s)
+	}
+	want := []Pill{Placebo, Aspirin, Acetaminophen}
+	if got := AllPill(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("AllPill() = %v, want %v", got, want)
+	}
+	for i, v := range want {
+		if o := v.Ordinal(); o != i {
+			t.Errorf("%v.Ordinal() = %d, want %d", v, o, i)
+		}
+	}
+}
+`)
+
+	dir = writePackage(t, map[string]string{"pill.go": deprecatedPillCode})
+	if out := runYamlenums(t, dir, "-type=Pill", "-deprecated=warn"); !strings.Contains(out, "constant Paracetamol of type Pill is deprecated") {
+		t.Errorf("unexpected output: %s", out)
+	}
+
+	dir = writePackage(t, map[string]string{"pill.go": deprecatedPillCode})
+	runYamlenums(t, dir, "-type=Pill", "-deprecated=reject")
+	goTest(t, dir, `
+package painkiller
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestDeprecated(t *testing.T) {
+	var p Pill
+	if err := yaml.Unmarshal([]byte("Paracetamol"), &p); err == nil {
+		t.Error("unmarshaling Paracetamol succeeded")
+	}
+	if err := yaml.Unmarshal([]byte("Acetaminophen"), &p); err != nil || p != Acetaminophen {
+		t.Errorf("unmarshaling Acetaminophen gives %v, %v", p, err)
+	}
+}
+`)
+	runYamlenumsFail(t, dir, "-type=Pill", "-deprecated=ignore")
+}
+
+func TestXML(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": pillCode})
+	runYamlenums(t, dir, "-type=Pill", "-xml", "-errtype")
+	goTest(t, dir, `
+package painkiller
+
+import (
+	"encoding/xml"
+	"errors"
+	"testing"
+)
+
+type prescription struct {
+	Pill Pill `+"`xml:\"pill\"`"+`
+}
+
+func TestXML(t *testing.T) {
+	b, err := xml.Marshal(prescription{Pill: Acetaminophen})
+	if want := "<prescription><pill>Paracetamol</pill></prescription>"; err != nil || string(b) != want {
+		t.Errorf("marshaling gives %s, %v", b, err)
+	}
+	var p prescription
+	if err := xml.Unmarshal([]byte("<prescription><pill>Ibuprofen</pill></prescription>"), &p); err != nil || p.Pill != Ibuprofen {
+		t.Errorf("unmarshaled %v, %v", p.Pill, err)
+	}
+	for _, s := range []string{"<pill>Asprin</pill>", "<pill/>", "<pill></pill>"} {
+		var e *InvalidPillError
+		if err := xml.Unmarshal([]byte("<prescription>"+s+"</prescription>"), &p); !errors.As(err, &e) {
+			t.Errorf("unmarshaling %s gives %v", s, err)
+		}
+	}
+	if _, err := xml.Marshal(prescription{Pill: Pill(12)}); err == nil {
+		t.Error("marshaling Pill(12) succeeded")
+	}
+}
+`)
+
+	dir = writePackage(t, map[string]string{"pill.go": pillCode})
+	runYamlenums(t, dir, "-type=Pill", "-xml", "-emptyzero")
+	goTest(t, dir, `
+package painkiller
+
+import (
+	"encoding/xml"
+	"testing"
+)
+
+func TestXMLEmpty(t *testing.T) {
+	p := Aspirin
+	if err := xml.Unmarshal([]byte("<pill/>"), &p); err != nil || p != Placebo {
+		t.Errorf("unmarshaled %v, %v", p, err)
+	}
+}
+`)
+}
+
+func TestIgnoreCase(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": pillCode})
+	runYamlenums(t, dir, "-type=Pill", "-ignorecase")
+	goTest(t, dir, `
+package painkiller
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestIgnoreCase(t *testing.T) {
+	for _, s := range []string{"aspirin", "Aspirin", "ASPIRIN"} {
+		var p Pill
+		if err := yaml.Unmarshal([]byte(s), &p); err != nil || p != Aspirin {
+			t.Errorf("unmarshaling %s gives %v, %v", s, p, err)
+		}
+	}
+}
+`)
+
+	dir = writePackage(t, map[string]string{"pill.go": `
+package painkiller
+
+type Pill int
+
+const (
+	Placebo Pill = iota
+	Aspirin
+	ASPIRIN
+)
+`})
+	out := runYamlenumsFail(t, dir, "-type=Pill", "-ignorecase")
+	if !strings.Contains(out, `Aspirin and ASPIRIN are marshaled to "Aspirin" and "ASPIRIN", which are the same ignoring case`) {
+		t.Errorf("names differing only in case fail with:\n%s", out)
+	}
+	runYamlenums(t, dir, "-type=Pill")
+	// Aliases may differ only in case, naming the same value anyway.
+	dir = writePackage(t, map[string]string{"pill.go": pillCode + "\nconst ASPIRIN = Aspirin\n"})
+	runYamlenums(t, dir, "-type=Pill", "-ignorecase")
+}
+
+func TestAcceptInt(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": pillCode})
+	runYamlenums(t, dir, "-type=Pill", "-acceptint")
+	goTest(t, dir, `
+package painkiller
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestAcceptInt(t *testing.T) {
+	for s, want := range map[string]Pill{"1": Aspirin, "Ibuprofen": Ibuprofen, "0": Placebo} {
+		var p Pill
+		if err := yaml.Unmarshal([]byte(s), &p); err != nil || p != want {
+			t.Errorf("unmarshaling %s gives %v, %v", s, p, err)
+		}
+	}
+	for _, s := range []string{"4", "-1", "Asprin", "4294967297"} {
+		var p Pill
+		if err := yaml.Unmarshal([]byte(s), &p); err == nil {
+			t.Errorf("unmarshaling %s succeeded", s)
+		}
+	}
+}
+`)
+}
+
+func TestMarshalInt(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": pillCode})
+	runYamlenums(t, dir, "-type=Pill", "-marshalint")
+	goTest(t, dir, `
+package painkiller
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestMarshalInt(t *testing.T) {
+	for p, want := range map[Pill]string{Placebo: "0\n", Aspirin: "1\n", Acetaminophen: "3\n"} {
+		if b, err := yaml.Marshal(p); err != nil || string(b) != want {
+			t.Errorf("marshaling %v gives %q, %v", p, b, err)
+		}
+	}
+	for s, want := range map[string]Pill{"1": Aspirin, "Ibuprofen": Ibuprofen, "Acetaminophen": Paracetamol} {
+		var p Pill
+		if err := yaml.Unmarshal([]byte(s), &p); err != nil || p != want {
+			t.Errorf("unmarshaling %s gives %v, %v", s, p, err)
+		}
+	}
+	for _, p := range []Pill{Placebo, Aspirin, Ibuprofen, Paracetamol} {
+		b, err := yaml.Marshal(p)
+		var got Pill
+		if err == nil {
+			err = yaml.Unmarshal(b, &got)
+		}
+		if err != nil || got != p {
+			t.Errorf("round trip of %v gives %v, %v", p, got, err)
+		}
+	}
+	if _, err := yaml.Marshal(Pill(12)); err == nil {
+		t.Error("marshaling Pill(12) succeeded")
+	}
+}
+`)
+}
+
+func TestYAMLTag(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": pillCode})
+	runYamlenums(t, dir, "-type=Pill", "-yamltag=!pill")
+	goTest(t, dir, `
+package painkiller
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestYAMLTag(t *testing.T) {
+	b, err := yaml.Marshal(map[string]Pill{"pill": Acetaminophen})
+	if want := "pill: !pill Paracetamol\n"; err != nil || string(b) != want {
+		t.Errorf("marshaling gives %q, %v", b, err)
+	}
+	for _, s := range []string{"!pill Aspirin", "Aspirin", "!!str Aspirin"} {
+		var p Pill
+		if err := yaml.Unmarshal([]byte(s), &p); err != nil || p != Aspirin {
+			t.Errorf("unmarshaling %s gives %v, %v", s, p, err)
+		}
+	}
+	var p Pill
+	if err := yaml.Unmarshal([]byte("!pill Asprin"), &p); err == nil {
+		t.Error("unmarshaling !pill Asprin succeeded")
+	}
+}
+`)
+	for _, args := range [][]string{{"-yamltag=pill"}, {"-yamltag=!pi ll"}, {"-yamltag=!pill", "-yamlpkg=v2"}, {"-yamltag=!pill", "-marshalint"}} {
+		runYamlenumsFail(t, dir, append([]string{"-type=Pill"}, args...)...)
+	}
+}
+
+func TestPtrMarshal(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": pillCode})
+	runYamlenums(t, dir, "-type=Pill", "-ptrmarshal", "-gentests")
+	goTest(t, dir, `
+package painkiller
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestPtrMarshal(t *testing.T) {
+	p := Aspirin
+	if b, err := yaml.Marshal(&p); err != nil || string(b) != "Aspirin\n" {
+		t.Errorf("marshaling &p gives %q, %v", b, err)
+	}
+	// Values don't satisfy yaml.Marshaler, so they marshal as integers.
+	if b, err := yaml.Marshal(p); err != nil || string(b) != "1\n" {
+		t.Errorf("marshaling p gives %q, %v", b, err)
+	}
+	var none *Pill
+	if b, err := yaml.Marshal(struct{ P *Pill }{none}); err != nil || string(b) != "p: null\n" {
+		t.Errorf("marshaling a nil pointer gives %q, %v", b, err)
+	}
+}
+`)
+	dir = writePackage(t, map[string]string{"pill.go": pillCode})
+	runYamlenums(t, dir, "-type=Pill", "-ptrmarshal", "-marshalint", "-gentests")
+	goTest(t, dir, "package painkiller\n")
+}
+
+func TestValid(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": pillCode})
+	runYamlenums(t, dir, "-type=Pill", "-valid")
+	goTest(t, dir, `
+package painkiller
+
+import "testing"
+
+func TestIsValid(t *testing.T) {
+	if !Aspirin.IsValid() {
+		t.Error("Aspirin is not valid")
+	}
+	n := 12
+	if Pill(n).IsValid() {
+		t.Error("Pill(12) is valid")
+	}
+}
+`)
+}
+
+func TestAll(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": pillCode})
+	runYamlenums(t, dir, "-type=Pill", "-all")
+	goTest(t, dir, `
+package painkiller
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestAllPill(t *testing.T) {
+	want := []Pill{Placebo, Aspirin, Ibuprofen, Paracetamol}
+	if got := AllPill(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("AllPill() = %v, want %v", got, want)
+	}
+}
+`)
+}
+
+func TestNames(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": pillCode})
+	runYamlenums(t, dir, "-type=Pill", "-names")
+	goTest(t, dir, `
+package painkiller
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestPillNames(t *testing.T) {
+	want := []string{"Placebo", "Aspirin", "Ibuprofen", "Paracetamol"}
+	if got := PillNames(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("PillNames() = %v, want %v", got, want)
+	}
+}
+`)
+
+	dir = writePackage(t, map[string]string{"pill.go": prefixedPillCode})
+	runYamlenums(t, dir, "-type=Pill", "-names", "-trimprefix=Pill", "-transform=upper")
+	goTest(t, dir, `
+package painkiller
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestPillNames(t *testing.T) {
+	want := []string{"PLACEBO", "ASPIRIN", "IBUPROFEN"}
+	if got := PillNames(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("PillNames() = %v, want %v", got, want)
+	}
+	for _, s := range PillNames() {
+		if _, err := ParsePill(s); err != nil {
+			t.Error(err)
+		}
+	}
+}
+`)
+}
+
+func TestDocs(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": `
+package painkiller
+
+type Pill int
+
+const (
+	Placebo Pill = iota
+	// Aspirin thins the blood.
+	//
+	// Take it with water.
+	Aspirin
+	/* Ibuprofen reduces
+inflammation. */
+	Ibuprofen
+)
+`})
+	for _, args := range [][]string{{"-all", "-names"}, {"-all", "-names", "-noformat"}} {
+		runYamlenums(t, dir, append([]string{"-type=Pill", "-docs"}, args...)...)
+		cmd := exec.Command("go", "vet", ".")
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("the code generated with %v doesn't compile: %v\n%s", args, err, out)
+		}
+	}
+	runYamlenums(t, dir, "-type=Pill", "-docs", "-all", "-names")
+	code, err := ioutil.ReadFile(filepath.Join(dir, "pill_yamlenums.go"))
+	must(t, err)
+	want := `		// Aspirin thins the blood.
+		//
+		// Take it with water.
+		Aspirin,
+		// Ibuprofen reduces
+		// inflammation.
+		Ibuprofen,
+`
+	if !strings.Contains(string(code), want) {
+		t.Errorf("the doc comments aren't carried into AllPill:\n%s", code)
+	}
+	if !strings.Contains(string(code), "// Take it with water.\n\t\t\"Aspirin\",") {
+		t.Errorf("the doc comments aren't carried into PillNames:\n%s", code)
+	}
+	if out := runYamlenumsFail(t, dir, "-type=Pill", "-docs"); !strings.Contains(out, "the flag -docs needs -all or -names") {
+		t.Errorf("-docs alone fails with:\n%s", out)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": pillCode, "perm.go": permCode})
+	for _, args := range [][]string{
+		{"-type=Pill"},
+		{"-type=Pill", "-transform=kebab", "-emptyzero"},
+		{"-type=Pill", "-ignorecase", "-lookup=binary"},
+		{"-type=Pill", "-marshalint", "-fallback=Placebo"},
+		{"-type=Perm", "-bitflags"},
+		{"-type=Perm", "-bitflags", "-flagstring", "-transform=lower"},
+	} {
+		runYamlenums(t, dir, append(args, "-verify")...)
+	}
+
+	// The integers of -marshalint are looked up as strings first, and the
+	// names of -flagstring are trimmed, which only -verify finds out.
+	dir = writePackage(t, map[string]string{"level.go": `
+package painkiller
+
+type Level int
+
+const (
+	Low Level = iota // 1
+	High
+)
+
+type Perm uint
+
+const (
+	Read Perm = 1 << iota // yamlenums:" read"
+	Write
+)
+`})
+	for _, tc := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-type=Level", "-marshalint", "-linecomment"}, `verifying values of type Level: High = 1 is marshaled to 1, which unmarshals to 0`},
+		{[]string{"-type=Perm", "-bitflags", "-flagstring"}, `verifying values of type Perm: Read is marshaled to " read", which unmarshals to no value`},
+	} {
+		runYamlenums(t, dir, tc.args...)
+		if out := runYamlenumsFail(t, dir, append(tc.args, "-verify")...); !strings.Contains(out, tc.want) {
+			t.Errorf("-verify with %v fails with:\n%s", tc.args, out)
+		}
+	}
+
+	dir = writePackage(t, map[string]string{"pill.go": pillCode + `
+func (p Pill) String() string { return "pill" }
+`})
+	out := runYamlenumsFail(t, dir, "-type=Pill", "-verify")
+	if !strings.Contains(out, "the flag -verify can't work out the output of the String method of Pill") {
+		t.Errorf("a String method fails with:\n%s", out)
+	}
+}
+
+func TestYAMLBoth(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": pillCode})
+	runYamlenums(t, dir, "-type=Pill", "-yamlpkg=both", "-gentests")
+	code, err := ioutil.ReadFile(filepath.Join(dir, "pill_yamlenums.go"))
+	must(t, err)
+	if strings.Contains(string(code), "gopkg.in/yaml") {
+		t.Errorf("the code generated for both versions imports go-yaml:\n%s", code)
+	}
+	goTest(t, dir, `
+package painkiller
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+var _ interface{ UnmarshalYAML(func(interface{}) error) error } = (*Pill)(nil)
+
+func TestUnmarshalV3(t *testing.T) {
+	var p Pill
+	if err := yaml.Unmarshal([]byte("Ibuprofen"), &p); err != nil || p != Ibuprofen {
+		t.Errorf("Ibuprofen is unmarshaled as %v, %v", p, err)
+	}
+	if err := yaml.Unmarshal([]byte("xyz"), &p); err == nil || !strings.Contains(err.Error(), "invalid Pill") {
+		t.Errorf("xyz is unmarshaled with %v", err)
+	}
+}
+`)
+}
+
+func TestYAMLImportName(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": pillCode, "config.go": `
+package painkiller
+
+import yamlv3 "gopkg.in/yaml.v3"
+
+func decode(b []byte, v interface{}) error { return yamlv3.Unmarshal(b, v) }
+`})
+	runYamlenums(t, dir, "-type=Pill", "-gentests", "-example")
+	code, err := ioutil.ReadFile(filepath.Join(dir, "pill_yamlenums.go"))
+	must(t, err)
+	if !strings.Contains(string(code), `yamlv3 "gopkg.in/yaml.v3"`) || !strings.Contains(string(code), "value *yamlv3.Node") {
+		t.Errorf("the alias of the package isn't used:\n%s", code)
+	}
+	cmd := exec.Command("go", "test", ".")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("go test with the alias: %v\n%s", err, out)
+	}
+
+	dir = writePackage(t, map[string]string{"pill.go": pillCode + `
+var yaml = "painkiller.yaml"
+`})
+	runYamlenums(t, dir, "-type=Pill", "-gentests")
+	code, err = ioutil.ReadFile(filepath.Join(dir, "pill_yamlenums.go"))
+	must(t, err)
+	if !strings.Contains(string(code), `goyaml "gopkg.in/yaml.v3"`) {
+		t.Errorf("the import doesn't avoid the variable yaml:\n%s", code)
+	}
+	cmd = exec.Command("go", "test", ".")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("go test with the variable yaml: %v\n%s", err, out)
+	}
+}
+
+func TestGoVersion(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": pillCode})
+	path := filepath.Join(dir, "pill_yamlenums.go")
+	runYamlenums(t, dir, "-type=Pill", "-errtype")
+	code, err := ioutil.ReadFile(path)
+	must(t, err)
+	runYamlenums(t, dir, "-type=Pill", "-errtype", "-go=1.12")
+	code12, err := ioutil.ReadFile(path)
+	must(t, err)
+	if string(code12) != strings.Replace(string(code), "-errtype;", "-errtype -go=1.12;", 1) {
+		t.Errorf("the code differs for Go 1.12:\n%s", code12)
+	}
+
+	// The language version of the module is the one targeted, so the code
+	// has to compile with it.
+	goMod12 := strings.Replace(goMod, "go 1.14", "go 1.12", 1)
+	must(t, ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte(goMod12), 0644))
+	runYamlenums(t, dir, "-type=Pill", "-ignorecase", "-errtype", "-gentests")
+	code, err = ioutil.ReadFile(path)
+	must(t, err)
+	if strings.Contains(string(code), "%w") {
+		t.Errorf("the errors are wrapped for the go directive 1.12:\n%s", code)
+	}
+	goTest(t, dir, `
+package painkiller
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestUnmarshal(t *testing.T) {
+	var p Pill
+	if err := yaml.Unmarshal([]byte("xyz"), &p); err == nil || !strings.Contains(err.Error(), "at line 1, column 1") {
+		t.Errorf("xyz is unmarshaled with %v", err)
+	}
+	if _, ok := p.UnmarshalYAML(&yaml.Node{Kind: yaml.ScalarNode, Value: "xyz"}).(*InvalidPillError); !ok {
+		t.Error("UnmarshalYAML doesn't return *InvalidPillError")
+	}
+}
+`)
+
+	for _, version := range []string{"2", "1.x", "go1.16"} {
+		if out := runYamlenumsFail(t, dir, "-type=Pill", "-go="+version); !strings.Contains(out, "malformed Go version") {
+			t.Errorf("-go=%s fails with:\n%s", version, out)
+		}
+	}
+
+	// Only custom templates are told the version, which go directives of
+	// release candidates give too.
+	tmpl := filepath.Join(dir, "wrap.tmpl")
+	must(t, ioutil.WriteFile(tmpl, []byte("package {{.PackageName}}\n\nconst wrap = {{.WrapErrors}}\n"), 0644))
+	for directive, want := range map[string]string{"go 1.12": "false", "go 1.21rc1": "true", "go 1.21.0": "true"} {
+		must(t, ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte(strings.Replace(goMod, "go 1.14", directive, 1)), 0644))
+		runYamlenums(t, dir, "-type=Pill")
+		runYamlenums(t, dir, "-type=Pill", "-template="+tmpl)
+		code, err := ioutil.ReadFile(path)
+		must(t, err)
+		if !strings.Contains(string(code), "const wrap = "+want) {
+			t.Errorf("WrapErrors for %q isn't %s:\n%s", directive, want, code)
+		}
+	}
+}
+
+func TestLineComment(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": `
+package painkiller
+
+type Pill int
+
+const (
+	Placebo Pill = iota
+	Aspirin     // aspirin-500mg
+	Ibuprofen   // ibuprofen-200mg
+)
+`})
+	runYamlenums(t, dir, "-type=Pill", "-linecomment", "-text")
+	goTest(t, dir, `
+package painkiller
+
+import "testing"
+
+func TestLineComment(t *testing.T) {
+	for p, want := range map[Pill]string{Placebo: "Placebo", Aspirin: "aspirin-500mg"} {
+		if b, err := p.MarshalText(); err != nil || string(b) != want {
+			t.Errorf("marshaling %d gives %s, %v", p, b, err)
+		}
+	}
+	if v, err := ParsePill("ibuprofen-200mg"); err != nil || v != Ibuprofen {
+		t.Errorf("ParsePill(%q) = %v, %v", "ibuprofen-200mg", v, err)
+	}
+}
+`)
+}
+
+var prefixedPillCode = `
+package painkiller
+
+type Pill int
+
+const (
+	PillPlacebo Pill = iota
+	PillAspirin
+	PillIbuprofen
+)
+`
+
+func TestTrimPrefix(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": prefixedPillCode})
+	runYamlenums(t, dir, "-type=Pill", "-trimprefix=Tablet,Pill", "-text")
+	goTest(t, dir, `
+package painkiller
+
+import "testing"
+
+func TestTrimPrefix(t *testing.T) {
+	if b, err := PillAspirin.MarshalText(); err != nil || string(b) != "Aspirin" {
+		t.Errorf("PillAspirin.MarshalText() = %s, %v", b, err)
+	}
+	if v, err := ParsePill("Ibuprofen"); err != nil || v != PillIbuprofen {
+		t.Errorf("ParsePill(%q) = %v, %v", "Ibuprofen", v, err)
+	}
+}
+`)
+}
+
+func TestTrimPrefixEmpty(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": prefixedPillCode})
+	if out := runYamlenumsFail(t, dir, "-type=Pill", "-trimprefix=PillAspirin"); !strings.Contains(out, "leaves nothing") {
+		t.Errorf("unexpected output: %s", out)
+	}
+}
+
+var statusCode = `
+package painkiller
+
+type Status int
+
+const (
+	ActiveState Status = iota
+	ClosedState
+)
+`
+
+func TestTrimSuffix(t *testing.T) {
+	dir := writePackage(t, map[string]string{"status.go": statusCode})
+	runYamlenums(t, dir, "-type=Status", "-trimsuffix=State")
+	goTest(t, dir, `
+package painkiller
+
+import "testing"
+
+func TestTrimSuffix(t *testing.T) {
+	if v, err := ParseStatus("Closed"); err != nil || v != ClosedState {
+		t.Errorf("ParseStatus(%q) = %v, %v", "Closed", v, err)
+	}
+}
+`)
+}
+
+func TestTrimCollision(t *testing.T) {
+	dir := writePackage(t, map[string]string{"status.go": statusCode + "const Active Status = 2\n"})
+	if out := runYamlenumsFail(t, dir, "-type=Status", "-trimsuffix=State"); !strings.Contains(out, `both ActiveState and Active are marshaled to "Active"`) {
+		t.Errorf("unexpected output: %s", out)
+	}
+}
+
+func TestTransform(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": prefixedPillCode})
+	runYamlenums(t, dir, "-type=Pill", "-transform=snake", "-text")
+	goTest(t, dir, `
+package painkiller
+
+import "testing"
+
+func TestTransform(t *testing.T) {
+	for _, p := range []Pill{PillPlacebo, PillAspirin, PillIbuprofen} {
+		b, err := p.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var q Pill
+		if err := q.UnmarshalText(b); err != nil || q != p {
+			t.Errorf("%s unmarshaled to %d, %v", b, q, err)
+		}
+	}
+	if b, _ := PillAspirin.MarshalText(); string(b) != "pill_aspirin" {
+		t.Errorf("PillAspirin is marshaled to %s", b)
+	}
+}
+`)
+}
+
+func TestTransformCollision(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": prefixedPillCode + "const Pill_Aspirin Pill = 3\n"})
+	if out := runYamlenumsFail(t, dir, "-type=Pill", "-transform=snake"); !strings.Contains(out, `both PillAspirin and Pill_Aspirin are marshaled to "pill_aspirin"`) {
+		t.Errorf("unexpected output: %s", out)
+	}
+}
+
+func TestOverride(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": `
+package painkiller
+
+type Pill int
+
+const (
+	Placebo Pill = iota
+	// yamlenums:"acetylsalicylic acid"
+	Aspirin
+	Paracetamol // yamlenums:"acetaminophen"
+)
+`})
+	runYamlenums(t, dir, "-type=Pill", "-text", "-transform=lower")
+	goTest(t, dir, `
+package painkiller
+
+import "testing"
+
+func TestOverride(t *testing.T) {
+	for p, want := range map[Pill]string{
+		Placebo:     "placebo",
+		Aspirin:     "acetylsalicylic acid",
+		Paracetamol: "acetaminophen",
+	} {
+		if b, err := p.MarshalText(); err != nil || string(b) != want {
+			t.Errorf("marshaling %d gives %s, %v", p, b, err)
+		}
+		if v, err := ParsePill(want); err != nil || v != p {
+			t.Errorf("ParsePill(%q) = %v, %v", want, v, err)
+		}
+	}
+}
+`)
+}
+
+func TestOverrideCollision(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": `
+package painkiller
+
+type Pill int
+
+const (
+	Aspirin Pill = iota // yamlenums:"pill"
+	Paracetamol         // yamlenums:"pill"
+)
+`})
+	if out := runYamlenumsFail(t, dir, "-type=Pill"); !strings.Contains(out, `both Aspirin and Paracetamol are marshaled to "pill"`) {
+		t.Errorf("unexpected output: %s", out)
+	}
+}
+
+func TestStringType(t *testing.T) {
+	dir := writePackage(t, map[string]string{"env.go": `
+package painkiller
+
+type Env string
+
+const (
+	Prod Env = "production"
+	Dev  Env = "development"
+)
+`})
+	runYamlenums(t, dir, "-type=Env", "-text", "-stringer", "-acceptint")
+	goTest(t, dir, `
+package painkiller
+
+import "testing"
+
+func TestStringType(t *testing.T) {
+	if b, err := Prod.MarshalText(); err != nil || string(b) != "production" {
+		t.Errorf("Prod.MarshalText() = %s, %v", b, err)
+	}
+	if v, err := ParseEnv("development"); err != nil || v != Dev {
+		t.Errorf("ParseEnv(%q) = %v, %v", "development", v, err)
+	}
+	if s := Env("staging").String(); s != "Env(\"staging\")" {
+		t.Errorf("Env(%q).String() = %s", "staging", s)
+	}
+}
+`)
+}
+
+func TestStringTypeEmpty(t *testing.T) {
+	dir := writePackage(t, map[string]string{"env.go": `
+package painkiller
+
+type Env string
+
+const (
+	Unset Env = ""
+	Prod  Env = "production"
+)
+`})
+	for _, lookup := range []string{"map", "switch", "binary"} {
+		runYamlenums(t, dir, "-type=Env", "-lookup="+lookup, "-gentests")
+		goTest(t, dir, `
+package painkiller
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestStringTypeEmpty(t *testing.T) {
+	out, err := yaml.Marshal(map[string]Env{"env": Unset})
+	if err != nil || string(out) != "env: \"\"\n" {
+		t.Errorf("marshaling Unset gives %q, %v", out, err)
+	}
+	e := Prod
+	if err := yaml.Unmarshal([]byte("''"), &e); err != nil || e != Unset {
+		t.Errorf("unmarshaling '' gives %v, %v", e, err)
+	}
+}
+`)
+	}
+	if out := runYamlenumsFail(t, dir, "-type=Env", "-trimprefix=production"); !strings.Contains(out, "trimming leaves nothing of production") {
+		t.Errorf("-trimprefix=production fails with:\n%s", out)
+	}
+}
+
+func TestStringTypeAlias(t *testing.T) {
+	dir := writePackage(t, map[string]string{"speed.go": `
+package painkiller
+
+type Speed string
+
+const (
+	Slow     Speed = "slow"
+	Sluggish       = Slow
+	Fast     Speed = "fast"
+)
+`})
+	for _, lookup := range []string{"map", "switch", "binary"} {
+		runYamlenums(t, dir, "-type=Speed", "-lookup="+lookup, "-ignorecase", "-errtype", "-gentests")
+		goTest(t, dir, `
+package painkiller
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestStringTypeAlias(t *testing.T) {
+	out, err := yaml.Marshal(Sluggish)
+	if err != nil || string(out) != "slow\n" {
+		t.Errorf("marshaling Sluggish gives %q, %v", out, err)
+	}
+	var s Speed
+	if err := yaml.Unmarshal([]byte("SLOW"), &s); err != nil || s != Slow {
+		t.Errorf("unmarshaling SLOW gives %v, %v", s, err)
+	}
+	_, err = ParseSpeed("medium")
+	if err == nil || err.Error() != "invalid Speed \"medium\": valid values are [slow fast]" {
+		t.Errorf("ParseSpeed(\"medium\") = %v", err)
+	}
+}
+`)
+	}
+	runYamlenums(t, dir, "-type=Speed", "-schema")
+	schema, err := ioutil.ReadFile(filepath.Join(dir, "speed.schema.json"))
+	must(t, err)
+	if !strings.Contains(string(schema), `"enum": [
+    "slow",
+    "fast"
+  ]`) {
+		t.Errorf("the schema lists the strings other than once:\n%s", schema)
+	}
+}
+
+func TestAcceptIntRange(t *testing.T) {
+	dir := writePackage(t, map[string]string{"small.go": `
+package painkiller
+
+type Small int8
+
+const (
+	Negative Small = -1
+	Positive Small = 1
+)
+
+type Byte uint8
+
+const (
+	Low  Byte = 1
+	High Byte = 255
+)
+`})
+	runYamlenums(t, dir, "-type=Small,Byte", "-acceptint")
+	goTest(t, dir, `
+package painkiller
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestAcceptIntRange(t *testing.T) {
+	var s Small
+	if err := yaml.Unmarshal([]byte("-1"), &s); err != nil || s != Negative {
+		t.Errorf("unmarshaling -1 gives %v, %v", s, err)
+	}
+	if err := yaml.Unmarshal([]byte("255"), &s); err == nil {
+		t.Errorf("unmarshaling 255 into Small succeeded")
+	}
+	var b Byte
+	if err := yaml.Unmarshal([]byte("255"), &b); err != nil || b != High {
+		t.Errorf("unmarshaling 255 gives %v, %v", b, err)
+	}
+	for _, in := range []string{"257", "-1", "18446744073709551617"} {
+		if err := yaml.Unmarshal([]byte(in), &b); err == nil {
+			t.Errorf("unmarshaling %s into Byte succeeded", in)
+		}
+	}
+}
+`)
+}
+
+func TestGeneratedHeader(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": pillCode})
+	runYamlenums(t, dir, "-type=Pill")
+	src, err := ioutil.ReadFile(filepath.Join(dir, "pill_yamlenums.go"))
+	must(t, err)
+	// This is the convention go generate documents for generated files.
+	generated := regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+	if first := strings.SplitN(string(src), "\n", 2)[0]; !generated.MatchString(first) {
+		t.Errorf("the first line %q doesn't mark the file as generated", first)
+	}
+	src = []byte(runYamlenums(t, dir, "-type=Pill", "-nocmd", "-output=-"))
+	if first := strings.SplitN(string(src), "\n", 2)[0]; first != "// Code generated by yamlenums; DO NOT EDIT." || !generated.MatchString(first) {
+		t.Errorf("with -nocmd the first line is %q", first)
+	}
+}
+
+func TestYAMLPackage(t *testing.T) {
+	for _, version := range []string{"v2", "v3"} {
+		t.Run(version, func(t *testing.T) {
+			dir := writePackage(t, map[string]string{"pill.go": pillCode})
+			runYamlenums(t, dir, "-type=Pill", "-yamlpkg="+version)
+			// Code generated for v2 can be tested with v3 as it supports the
+			// obsolete form of UnmarshalYAML.
+			goTest(t, dir, `
+package painkiller
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestYAML(t *testing.T) {
+	b, err := yaml.Marshal(Aspirin)
+	if err != nil || string(b) != "Aspirin\n" {
+		t.Errorf("yaml.Marshal(Aspirin) = %q, %v", b, err)
+	}
+	var p Pill
+	if err := yaml.Unmarshal([]byte("Ibuprofen"), &p); err != nil || p != Ibuprofen {
+		t.Errorf("unmarshaled %v, %v", p, err)
+	}
+	if err := yaml.Unmarshal([]byte("Asprin"), &p); err == nil {
+		t.Error("unmarshaling Asprin succeeded")
+	}
+}
+`)
+		})
+	}
+}
+
+func TestErrType(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": pillCode})
+	runYamlenums(t, dir, "-type=Pill", "-errtype")
+	goTest(t, dir, `
+package painkiller
+
+import (
+	"errors"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestErrType(t *testing.T) {
+	var p Pill
+	err := yaml.Unmarshal([]byte("Asprin"), &p)
+	var e *InvalidPillError
+	if !errors.As(err, &e) || e.Value != "Asprin" {
+		t.Errorf("unmarshaling Asprin gives %v", err)
+	}
+	if _, err := ParsePill("Asprin"); !errors.As(err, &e) {
+		t.Errorf("ParsePill(%q) gives %v", "Asprin", err)
+	}
+}
+`)
+	runYamlenums(t, dir, "-type=Pill", "-errtype", "-acceptint")
+	goTest(t, dir, `
+package painkiller
+
+import (
+	"errors"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestErrType(t *testing.T) {
+	var p Pill
+	for _, s := range []string{"Asprin", "12"} {
+		err := yaml.Unmarshal([]byte(s), &p)
+		var e *InvalidPillError
+		if !errors.As(err, &e) || e.Value != s {
+			t.Errorf("unmarshaling %s gives %v", s, err)
+		}
+	}
+}
+`)
+}
+
+func TestErrorPosition(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": pillCode})
+	runYamlenums(t, dir, "-type=Pill")
+	goTest(t, dir, `
+package painkiller
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestErrorPosition(t *testing.T) {
+	var v struct {
+		Morning Pill
+		Evening Pill
+	}
+	err := yaml.Unmarshal([]byte("morning: Aspirin\nevening:   xyz\n"), &v)
+	if err == nil || !strings.Contains(err.Error(), "invalid Pill \"xyz\" at line 2, column 12: valid values are [Placebo Aspirin Ibuprofen Paracetamol Acetaminophen]") {
+		t.Errorf("unmarshaling gives %v", err)
+	}
+}
+`)
+	runYamlenums(t, dir, "-type=Pill", "-errtype")
+	goTest(t, dir, `
+package painkiller
+
+import (
+	"errors"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestErrorPosition(t *testing.T) {
+	var v struct {
+		Morning Pill
+		Evening Pill
+	}
+	err := yaml.Unmarshal([]byte("morning: Aspirin\nevening:   xyz\n"), &v)
+	var e *InvalidPillError
+	if !errors.As(err, &e) || e.Value != "xyz" || e.Line != 2 || e.Column != 12 {
+		t.Errorf("unmarshaling gives %v", err)
+	}
+	if want := "invalid Pill \"xyz\" at line 2, column 12: valid values are [Placebo Aspirin Ibuprofen Paracetamol Acetaminophen]"; e.Error() != want {
+		t.Errorf("the error reads %q, want %q", e.Error(), want)
+	}
+}
+`)
+}
+
+func TestScalarNode(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": pillCode})
+	runYamlenums(t, dir, "-type=Pill")
+	goTest(t, dir, `
+package painkiller
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestScalarNode(t *testing.T) {
+	for in, want := range map[string]string{
+		"name: Aspirin": "expected scalar for Pill, got mapping at line 1, column 1",
+		"[Aspirin]":     "expected scalar for Pill, got sequence at line 1, column 1",
+	} {
+		var doc yaml.Node
+		if err := yaml.Unmarshal([]byte(in), &doc); err != nil {
+			t.Fatal(err)
+		}
+		var p Pill
+		if err := p.UnmarshalYAML(doc.Content[0]); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("unmarshaling %s gives %v", in, err)
+		}
+	}
+}
+`)
+}
+
+func TestLookup(t *testing.T) {
+	for _, lookup := range []string{"map", "switch", "binary"} {
+		t.Run(lookup, func(t *testing.T) {
+			dir := writePackage(t, map[string]string{"pill.go": pillCode})
+			runYamlenums(t, dir, "-type=Pill", "-ignorecase", "-lookup="+lookup)
+			goTest(t, dir, `
+package painkiller
+
+import "testing"
+
+func TestLookup(t *testing.T) {
+	want := map[string]Pill{
+		"Placebo":       Placebo,
+		"Aspirin":       Aspirin,
+		"Ibuprofen":     Ibuprofen,
+		"Paracetamol":   Paracetamol,
+		"Acetaminophen": Acetaminophen,
+		"ibuprofen":     Ibuprofen,
+		"PLACEBO":       Placebo,
+	}
+	for s, w := range want {
+		if p, err := ParsePill(s); err != nil || p != w {
+			t.Errorf("ParsePill(%q) = %v, %v", s, p, err)
+		}
+	}
+	for _, s := range []string{"", "Asprin", "Zzz"} {
+		if _, err := ParsePill(s); err == nil {
+			t.Errorf("ParsePill(%q) succeeded", s)
+		}
+	}
+}
+`)
+		})
+	}
+}
+
+func TestTemplate(t *testing.T) {
+	dir := writePackage(t, map[string]string{
+		"pill.go": pillCode,
+		"names.tmpl": `
+// Code generated by yamlenums {{.Command}}; DO NOT EDIT.
+
+package {{.PackageName}}
+{{range $typename, $values := .TypesAndValues}}
+func {{$typename}}Names() []string {
+	return []string{ {{range $values}}{{if not .Alias}}{{printf "%q" .Str}}, {{end}}{{end}} }
+}
+{{end}}
+`,
+	})
+	runYamlenums(t, dir, "-type=Pill", "-template="+filepath.Join(dir, "names.tmpl"))
+	if got := methods(t, filepath.Join(dir, "pill_yamlenums.go")); len(got) != 0 {
+		t.Errorf("the built-in template is used, generated methods %v", got)
+	}
+	goTest(t, dir, `
+package painkiller
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPillNames(t *testing.T) {
+	want := []string{"Placebo", "Aspirin", "Ibuprofen", "Paracetamol"}
+	if got := PillNames(); !reflect.DeepEqual(got, want) {
+		t.Errorf("PillNames() = %v, want %v", got, want)
+	}
+}
+`)
+}
+
+func TestAllTypes(t *testing.T) {
+	dir := writePackage(t, map[string]string{
+		"pill.go":  pillCode,
+		"color.go": colorCode,
+		"empty.go": "package painkiller\n\ntype Empty int\n",
+	})
+	runYamlenums(t, dir, "-all-types")
+	for _, typeName := range []string{"Pill", "Color"} {
+		path := filepath.Join(dir, strings.ToLower(typeName)+"_yamlenums.go")
+		want := []string{typeName + ".MarshalYAML", typeName + ".UnmarshalYAML"}
+		if got := methods(t, path); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("%s declares %v, want %v", path, got, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "empty_yamlenums.go")); !os.IsNotExist(err) {
+		t.Errorf("generated code for Empty: %v", err)
+	}
+	runYamlenumsFail(t, dir, "-all-types", "-type=Pill")
+}
+
+func TestList(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": pillCode, "color.go": colorCode, "env.go": `
+package painkiller
+
+type Env string
+
+const Prod Env = "production"
+`})
+	out := runYamlenums(t, dir, "-list")
+	want := `Color	Red	0
+Color	Green	1
+Color	Blue	2
+Env	Prod	"production"
+Pill	Placebo	0
+Pill	Aspirin	1
+Pill	Ibuprofen	2
+Pill	Paracetamol	3
+Pill	Acetaminophen	3
+`
+	if out != want {
+		t.Errorf("-list prints\n%s\nwant\n%s", out, want)
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "*_yamlenums.go")); len(files) > 0 {
+		t.Errorf("-list writes %v", files)
+	}
+	if out := runYamlenumsFail(t, dir, "-list", "-type=Pill"); !strings.Contains(out, "the flag -list can't be used with -type") {
+		t.Errorf("-list with -type fails with:\n%s", out)
+	}
+}
+
+func TestGoFile(t *testing.T) {
+	dir := writePackage(t, map[string]string{
+		"pill.go": "//go:generate yamlenums -type=Pill -gofile\n" + pillCode,
+		"expired.go": `
+package painkiller
+
+const Expired Pill = 100
+`,
+	})
+	cmd := exec.Command("go", "generate", ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "PATH="+filepath.Dir(yamlenums)+string(os.PathListSeparator)+os.Getenv("PATH"))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("go generate: %v\n%s", err, out)
+	}
+	goTest(t, dir, `
+package painkiller
+
+import "testing"
+
+func TestGoFile(t *testing.T) {
+	if p, err := ParsePill("Aspirin"); err != nil || p != Aspirin {
+		t.Errorf("ParsePill(\"Aspirin\") = %v, %v", p, err)
+	}
+	if _, err := ParsePill("Expired"); err == nil {
+		t.Error("constants outside of $GOFILE are parsed")
+	}
+}
+`)
+	runYamlenumsFail(t, dir, "-type=Pill", "-gofile")
+}
+
+func TestDryRun(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": pillCode, "color.go": colorCode})
+	out := runYamlenums(t, dir, "-type=Pill,Color", "-dryrun")
+	for _, file := range []string{"pill_yamlenums.go", "color_yamlenums.go"} {
+		path := filepath.Join(dir, file)
+		if !regexp.MustCompile(`would write ` + regexp.QuoteMeta(path) + ` \([1-9][0-9]* bytes\)`).MatchString(out) {
+			t.Errorf("the output doesn't report %s:\n%s", path, out)
+		}
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s is written: %v", path, err)
+		}
+	}
+}
+
+func TestUpToDate(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": pillCode})
+	path := filepath.Join(dir, "pill_yamlenums.go")
+	runYamlenums(t, dir, "-type=Pill")
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	must(t, os.Chtimes(path, old, old))
+
+	runYamlenums(t, dir, "-type=Pill")
+	info, err := os.Stat(path)
+	must(t, err)
+	if !info.ModTime().Equal(old) {
+		t.Errorf("an up to date file is rewritten")
+	}
+	if out := runYamlenums(t, dir, "-type=Pill", "-dryrun"); strings.Contains(out, "would write") {
+		t.Errorf("-dryrun reports an up to date file:\n%s", out)
+	}
+
+	runYamlenums(t, dir, "-type=Pill", "-force")
+	info, err = os.Stat(path)
+	must(t, err)
+	if info.ModTime().Equal(old) {
+		t.Errorf("-force doesn't rewrite an up to date file")
+	}
+}
+
+func TestSQL(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": pillCode})
+	runYamlenums(t, dir, "-type=Pill", "-sql", "-errtype")
+	goTest(t, dir, `
+package painkiller
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+)
+
+var (
+	_ driver.Valuer = Placebo
+	_ sql.Scanner   = new(Pill)
+)
+
+func TestSQL(t *testing.T) {
+	if v, err := Aspirin.Value(); err != nil || v != "Aspirin" {
+		t.Errorf("Aspirin.Value() = %v, %v", v, err)
+	}
+	if _, err := Pill(12).Value(); err == nil {
+		t.Error("Pill(12).Value() succeeded")
+	}
+	for _, src := range []interface{}{"Ibuprofen", []byte("Ibuprofen")} {
+		var p Pill
+		if err := p.Scan(src); err != nil || p != Ibuprofen {
+			t.Errorf("scanning %#v gives %v, %v", src, p, err)
+		}
+	}
+	p := Aspirin
+	if err := p.Scan(nil); err != nil || p != Placebo {
+		t.Errorf("scanning NULL gives %v, %v", p, err)
+	}
+	var invalid *InvalidPillError
+	if err := p.Scan("Asprin"); !errors.As(err, &invalid) || invalid.Value != "Asprin" {
+		t.Errorf("scanning Asprin gives %v", err)
+	}
+	if err := p.Scan(1); err == nil {
+		t.Error("scanning 1 succeeded")
+	}
+}
+`)
+}
+
+func TestFlagValue(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": pillCode})
+	runYamlenums(t, dir, "-type=Pill", "-flagvalue")
+	goTest(t, dir, `
+package painkiller
+
+import (
+	"flag"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestFlagValue(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	p := Aspirin
+	fs.Var(&p, "pill", "pill to take")
+	if err := fs.Parse([]string{"-pill=Ibuprofen"}); err != nil || p != Ibuprofen {
+		t.Errorf("parsing -pill=Ibuprofen gives %v, %v", p, err)
+	}
+	if s := fs.Lookup("pill").Value.String(); s != "Ibuprofen" {
+		t.Errorf("the flag value is %q", s)
+	}
+	err := fs.Parse([]string{"-pill=asprin"})
+	if err == nil || !strings.Contains(err.Error(), "valid values are [Placebo Aspirin Ibuprofen Paracetamol Acetaminophen]") {
+		t.Errorf("parsing -pill=asprin gives %v", err)
+	}
+}
+`)
+}
+
+func TestValidValues(t *testing.T) {
+	var src strings.Builder
+	src.WriteString("package painkiller\n\ntype Letter int\n\nconst (\n")
+	for i := 0; i < maxListed+5; i++ {
+		fmt.Fprintf(&src, "\tL%02d Letter = %d\n", i, i)
+	}
+	src.WriteString(")\n")
+	dir := writePackage(t, map[string]string{"pill.go": pillCode, "letter.go": src.String()})
+	runYamlenums(t, dir, "-type=Pill,Letter", "-single", "-errtype")
+	goTest(t, dir, `
+package painkiller
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidValues(t *testing.T) {
+	_, err := ParsePill("asprin")
+	if want := "invalid Pill \"asprin\": valid values are [Placebo Aspirin Ibuprofen Paracetamol Acetaminophen]"; err == nil || err.Error() != want {
+		t.Errorf("ParsePill(\"asprin\") fails with %v, want %s", err, want)
+	}
+	_, err = ParseLetter("L99")
+	if err == nil || !strings.HasSuffix(err.Error(), " L19 and 5 more]") {
+		t.Errorf("ParseLetter(\"L99\") fails with %v", err)
+	}
+}
+`)
+}
+
+func TestMarshalUnknown(t *testing.T) {
+	for _, mode := range []string{"error", "int"} {
+		t.Run(mode, func(t *testing.T) {
+			dir := writePackage(t, map[string]string{"pill.go": pillCode})
+			runYamlenums(t, dir, "-type=Pill", "-marshalunknown="+mode)
+			goTest(t, dir, `
+package painkiller
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestMarshalUnknown(t *testing.T) {
+	b, err := yaml.Marshal(Aspirin)
+	if err != nil || string(b) != "Aspirin\n" {
+		t.Errorf("yaml.Marshal(Aspirin) = %q, %v", b, err)
+	}
+	b, err = yaml.Marshal(Pill(12))
+	if mode := "`+mode+`"; mode == "int" && (err != nil || string(b) != "12\n") {
+		t.Errorf("yaml.Marshal(Pill(12)) = %q, %v", b, err)
+	} else if mode == "error" && err == nil {
+		t.Errorf("yaml.Marshal(Pill(12)) succeeded")
+	}
+}
+`)
+		})
+	}
+	dir := writePackage(t, map[string]string{"pill.go": pillCode})
+	runYamlenumsFail(t, dir, "-type=Pill", "-marshalunknown=string")
+}
+
+func TestGenTests(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": pillCode, "color.go": colorCode})
+	runYamlenums(t, dir, "-type=Pill,Color", "-gentests", "-ignorecase")
+	cmd := exec.Command("go", "test", "-v", ".")
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("go test: %v\n%s", err, out)
+	}
+	for _, test := range []string{"TestPillYAMLRoundTrip", "TestPillYAMLUnknown", "TestColorYAMLRoundTrip", "TestColorYAMLUnknown"} {
+		if !strings.Contains(string(out), "--- PASS: "+test) {
+			t.Errorf("%s doesn't pass:\n%s", test, out)
+		}
+	}
+	runYamlenumsFail(t, dir, "-type=Pill", "-gentests", "-yaml=false")
+}
+
+func TestGenTestsUnknown(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": `
+package painkiller
+
+type Pill int
+
+const (
+	Placebo     Pill = iota
+	UnknownPill
+	UNKNOWNPILL_ // yamlenums:"unknownpill_"
+)
+`})
+	runYamlenums(t, dir, "-type=Pill", "-gentests", "-ignorecase")
+	src, err := ioutil.ReadFile(filepath.Join(dir, "pill_yamlenums_test.go"))
+	must(t, err)
+	if !strings.Contains(string(src), `yaml.Marshal("UnknownPill__")`) {
+		t.Errorf("the unknown string names a constant:\n%s", src)
+	}
+	goTest(t, dir, "package painkiller\n")
+}
+
+func TestFiles(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": pillCode, "color.go": colorCode})
+	must(t, os.Mkdir(filepath.Join(dir, "sub"), 0755))
+	runYamlenums(t, filepath.Join(dir, "sub"), "-all-types", "../pill.go")
+	if _, err := os.Stat(filepath.Join(dir, "pill_yamlenums.go")); err != nil {
+		t.Errorf("no code generated for Pill: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "color_yamlenums.go")); !os.IsNotExist(err) {
+		t.Errorf("generated code for Color of an unlisted file: %v", err)
+	}
+	runYamlenumsFail(t, dir, "-type=Pill", "pill.go", ".")
+}
+
+func TestPackageName(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": pillCode})
+	for pkg, args := range map[string][]string{
+		"painkiller": {"-type=Pill", "-output=-"},
+		"meds":       {"-type=Pill", "-output=-", "-pkg=meds"},
+	} {
+		src := runYamlenums(t, dir, args...)
+		f, err := parser.ParseFile(token.NewFileSet(), "", src, parser.PackageClauseOnly)
+		must(t, err)
+		if f.Name.Name != pkg {
+			t.Errorf("running with %v generates package %s, want %s", args, f.Name.Name, pkg)
+		}
+	}
+}
+
+func TestBuildTag(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": pillCode})
+	runYamlenums(t, dir, "-type=Pill", "-buildtag=!noyaml", "-gentests")
+	for tags, want := range map[string]string{
+		"":       "[pill.go pill_yamlenums.go] [pill_yamlenums_test.go]",
+		"noyaml": "[pill.go] []",
+	} {
+		cmd := exec.Command("go", "list", "-tags="+tags, "-f", "{{.GoFiles}} {{.TestGoFiles}}", ".")
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("go list: %v\n%s", err, out)
+		}
+		if got := strings.TrimSpace(string(out)); got != want {
+			t.Errorf("with tags %q the package has files %s, want %s", tags, got, want)
+		}
+	}
+	goTest(t, dir, "package painkiller\n")
+	runYamlenumsFail(t, dir, "-type=Pill", "-buildtag=a && b")
+}
+
+func TestDupes(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": `
+package painkiller
+
+type Pill int
+
+const (
+	Placebo       Pill = 0
+	Paracetamol   Pill = 3
+	Acetaminophen Pill = 3
+)
+`})
+	const warning = "Acetaminophen has the same value as Paracetamol"
+	if out := runYamlenums(t, dir, "-type=Pill"); strings.Contains(out, warning) {
+		t.Errorf("-dupes=first warns:\n%s", out)
+	}
+	if out := runYamlenums(t, dir, "-type=Pill", "-dupes=warn"); !strings.Contains(out, "warning: "+warning) {
+		t.Errorf("-dupes=warn doesn't warn:\n%s", out)
+	}
+	if out := runYamlenumsFail(t, dir, "-type=Pill", "-dupes=error"); !strings.Contains(out, warning) {
+		t.Errorf("-dupes=error fails with:\n%s", out)
+	}
+}
+
+func TestAliases(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": pillCode})
+	runYamlenums(t, dir, "-type=Pill")
+	goTest(t, dir, `
+package painkiller
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestAliases(t *testing.T) {
+	for _, s := range []string{"Paracetamol", "Acetaminophen"} {
+		var p Pill
+		if err := yaml.Unmarshal([]byte(s), &p); err != nil || p != Paracetamol {
+			t.Errorf("unmarshaling %s gives %v, %v", s, p, err)
+		}
+		b, err := yaml.Marshal(p)
+		if err != nil || string(b) != "Paracetamol\n" {
+			t.Errorf("marshaling %s unmarshaled gives %q, %v", s, b, err)
+		}
+	}
+}
+`)
+}
+
+func TestNegative(t *testing.T) {
+	for _, lookup := range []string{"map", "switch", "binary"} {
+		t.Run(lookup, func(t *testing.T) {
+			dir := writePackage(t, map[string]string{"level.go": `
+package painkiller
+
+type Level int8
+
+const (
+	Unknown Level = -1
+	None    Level = 0
+	Low     Level = 1
+	Lowest        = Unknown
+)
+`})
+			runYamlenums(t, dir, "-type=Level", "-lookup="+lookup, "-acceptint", "-stringer", "-valid")
+			goTest(t, dir, `
+package painkiller
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestNegative(t *testing.T) {
+	for s, want := range map[string]Level{"Unknown": Unknown, "Lowest": Unknown, "None": None, "-1": Unknown, "0": None, "1": Low} {
+		var l Level
+		if err := yaml.Unmarshal([]byte(s), &l); err != nil || l != want {
+			t.Errorf("unmarshaling %s gives %v, %v", s, l, err)
+		}
+	}
+	for _, s := range []string{"-2", "-129", "255"} {
+		var l Level
+		if err := yaml.Unmarshal([]byte(s), &l); err == nil {
+			t.Errorf("unmarshaling %s succeeded", s)
+		}
+	}
+	b, err := yaml.Marshal(Unknown)
+	if err != nil || string(b) != "Unknown\n" {
+		t.Errorf("yaml.Marshal(Unknown) = %q, %v", b, err)
+	}
+	if s := Level(-5).String(); s != "Level(-5)" {
+		t.Errorf("Level(-5).String() = %q", s)
+	}
+	if !Unknown.IsValid() || Level(-5).IsValid() {
+		t.Error("IsValid misjudges negative values")
+	}
+}
+`)
+		})
+	}
+}
+
+func TestReproducible(t *testing.T) {
+	dir := writePackage(t, map[string]string{
+		"pill.go":   pillCode,
+		"color.go":  colorCode,
+		"status.go": statusCode,
+	})
+	args := []string{"-all-types", "-single", "-output=-", "-stringer", "-json", "-all", "-lookup=binary", "-gentests=false"}
+	first := runYamlenums(t, dir, args...)
+	for i := 0; i < 5; i++ {
+		if src := runYamlenums(t, dir, args...); src != first {
+			t.Fatalf("run %d generates different code:\n%s\nafter\n%s", i+2, src, first)
+		}
+	}
+}
+
+func TestVerbose(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": pillCode})
+	if out := runYamlenums(t, dir, "-type=Pill"); len(out) > 0 {
+		t.Errorf("yamlenums logs without -verbose:\n%s", out)
+	}
+	out := runYamlenums(t, dir, "-type=Pill", "-verbose")
+	for _, want := range []string{
+		"parsed package painkiller from " + filepath.Join(dir, "pill.go"),
+		`found constants of type Pill: Placebo = 0 as "Placebo", Aspirin = 1 as "Aspirin"`,
+		`Acetaminophen = 3 as "Acetaminophen"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("the output misses %s:\n%s", want, out)
+		}
+	}
+}
+
+func TestMustParse(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": pillCode})
+	runYamlenums(t, dir, "-type=Pill", "-mustparse", "-parse=false")
+	goTest(t, dir, `
+package painkiller
+
+import "testing"
+
+func TestMustParse(t *testing.T) {
+	if p := MustParsePill("Aspirin"); p != Aspirin {
+		t.Errorf("MustParsePill(\"Aspirin\") = %v", p)
+	}
+	defer func() {
+		err, _ := recover().(error)
+		if _, parseErr := ParsePill("Asprin"); err == nil || err.Error() != parseErr.Error() {
+			t.Errorf("MustParsePill(\"Asprin\") panics with %v", err)
+		}
+	}()
+	MustParsePill("Asprin")
+	t.Error("MustParsePill(\"Asprin\") doesn't panic")
+}
+`)
+}
+
+func TestImports(t *testing.T) {
+	for _, args := range [][]string{
+		{"-yaml=false"},
+		{"-yaml=false", "-parse=false"},
+		{"-yaml=false", "-json"},
+		{"-yaml=false", "-text", "-ignorecase"},
+		{"-yaml=false", "-sql", "-lookup=binary"},
+		{"-yaml=false", "-stringer", "-valid", "-all"},
+		{"-yaml=false", "-flagvalue", "-errtype", "-mustparse"},
+		{"-yamlpkg=v2"},
+		{"-yamlpkg=v2", "-acceptint", "-lookup=switch"},
+		{"-yamlpkg=v2", "-ptrmarshal"},
+		{"-yamlpkg=both", "-acceptint", "-gentests"},
+		{"-ptrmarshal", "-gentests"},
+		{"-yamltag=!enum", "-ptrmarshal", "-gentests"},
+		{"-example", "-gentests"},
+		{"-json", "-text", "-sql", "-ignorecase", "-lookup=binary"},
+		{"-marshalunknown=int", "-acceptint", "-gentests"},
+		{"-yamlpkg=sigs"},
+		{"-validate"},
+		{"-validate", "-errtype"},
+		{"-yamlpkg=sigs", "-json", "-acceptint"},
+		{"-yaml=false", "-parse=false", "-ordinal", "-navigate", "-namefunc", "-names"},
+		{"-yaml=false", "-exportmaps", "-lookup=switch"},
+		{"-yaml=false", "-parse=false", "-xml"},
+	} {
+		args := args
+		t.Run(strings.Join(args, " "), func(t *testing.T) {
+			t.Parallel()
+			dir := writePackage(t, map[string]string{"pill.go": pillCode, "env.go": `
+package painkiller
+
+type Env string
+
+const Prod Env = "production"
+`})
+			runYamlenums(t, dir, append([]string{"-type=Pill,Env", "-single"}, args...)...)
+			cmd := exec.Command("go", "vet", ".")
+			cmd.Dir = dir
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Errorf("the generated code doesn't compile: %v\n%s", err, out)
+			}
+		})
+	}
+}
+
+func TestSigsYAML(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": pillCode})
+	runYamlenums(t, dir, "-type=Pill", "-yamlpkg=sigs")
+	want := []string{"Pill.MarshalJSON", "Pill.UnmarshalJSON"}
+	if got := methods(t, filepath.Join(dir, "pill_yamlenums.go")); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("the generated methods are %v, want %v", got, want)
+	}
+	// sigs.k8s.io/yaml decodes the JSON converted from YAML with
+	// encoding/json, so that's what the methods are tested with.
+	goTest(t, dir, `
+package painkiller
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSigsYAML(t *testing.T) {
+	var p Pill
+	if err := json.Unmarshal([]byte("\"Ibuprofen\""), &p); err != nil || p != Ibuprofen {
+		t.Errorf("unmarshaled %v, %v", p, err)
+	}
+	if b, err := json.Marshal(Aspirin); err != nil || string(b) != "\"Aspirin\"" {
+		t.Errorf("json.Marshal(Aspirin) = %s, %v", b, err)
+	}
+}
+`)
+}
+
+func TestEmptyZero(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": pillCode})
+	runYamlenums(t, dir, "-type=Pill", "-emptyzero")
+	goTest(t, dir, `
+package painkiller
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestEmptyZero(t *testing.T) {
+	var c struct{ Pill Pill }
+	c.Pill = Aspirin
+	if err := yaml.Unmarshal([]byte("pill: \"\""), &c); err != nil || c.Pill != Placebo {
+		t.Errorf("unmarshaling an empty string gives %v, %v", c.Pill, err)
+	}
+	p := Aspirin
+	if err := p.UnmarshalYAML(&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null"}); err != nil || p != Placebo {
+		t.Errorf("unmarshaling null gives %v, %v", p, err)
+	}
+	if err := yaml.Unmarshal([]byte("pill: Ibuprofen"), &c); err != nil || c.Pill != Ibuprofen {
+		t.Errorf("unmarshaled %v, %v", c.Pill, err)
+	}
+}
+`)
+	runYamlenums(t, dir, "-type=Pill")
+	goTest(t, dir, `
+package painkiller
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestEmptyZero(t *testing.T) {
+	var p Pill
+	if err := yaml.Unmarshal([]byte("\"\""), &p); err == nil {
+		t.Error("unmarshaling an empty string succeeded without -emptyzero")
+	}
+}
+`)
+
+	dir = writePackage(t, map[string]string{"dose.go": `
+package painkiller
+
+type Dose int
+
+const (
+	Low Dose = iota + 1
+	High
+)
+
+type Env string
+
+const Prod Env = "production"
+`})
+	for _, typeName := range []string{"Dose", "Env"} {
+		if out := runYamlenumsFail(t, dir, "-type="+typeName, "-emptyzero"); !strings.Contains(out, "the flag -emptyzero needs a constant of the zero value, "+typeName+" has none") {
+			t.Errorf("-emptyzero for %s fails with:\n%s", typeName, out)
+		}
+	}
+}
+
+func TestScalarStrings(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": pillCode, "perm.go": permCode})
+	runYamlenums(t, dir, "-type=Pill")
+	runYamlenums(t, dir, "-type=Perm", "-bitflags")
+	goTest(t, dir, `
+package painkiller
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// UnmarshalYAML takes the strings of the scalars as Decode gives them.
+func TestScalarStrings(t *testing.T) {
+	for _, src := range []string{"Aspirin", "'Aspirin'", "\"Aspirin\"", "!!str Aspirin", "!!binary QXNwaXJpbg=="} {
+		var p Pill
+		if err := yaml.Unmarshal([]byte(src), &p); err != nil || p != Aspirin {
+			t.Errorf("unmarshaling %s gives %v, %v", src, p, err)
+		}
+	}
+	var c struct{ A, B Pill }
+	if err := yaml.Unmarshal([]byte("a: &p Ibuprofen\nb: *p\n"), &c); err != nil || c.A != Ibuprofen || c.B != Ibuprofen {
+		t.Errorf("unmarshaling an alias gives %v, %v", c, err)
+	}
+	for _, src := range []string{"!!binary QXNwaXJpbg", "Asprin"} {
+		var p Pill
+		if err := yaml.Unmarshal([]byte(src), &p); err == nil {
+			t.Errorf("unmarshaling %s succeeded", src)
+		}
+	}
+	var perm Perm
+	if err := yaml.Unmarshal([]byte("[Read, !!binary V3JpdGU=]"), &perm); err != nil || perm != Read|Write {
+		t.Errorf("unmarshaling the flags gives %v, %v", perm, err)
+	}
+}
+`)
+}
+
+// BenchmarkScalarString compares taking the string of a scalar node, as the
+// generated UnmarshalYAML does, with decoding it.
+func BenchmarkScalarString(b *testing.B) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte("Paracetamol"), &doc); err != nil {
+		b.Fatal(err)
+	}
+	value := doc.Content[0]
+	b.Run("Decode", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var s string
+			if err := value.Decode(&s); err != nil || s != "Paracetamol" {
+				b.Fatalf("decoded %q, %v", s, err)
+			}
+		}
+	})
+	b.Run("Value", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			s := value.Value
+			switch value.ShortTag() {
+			case "!!null":
+				s = ""
+			case "!!binary":
+				b.Fatal("the scalar is binary")
+			}
+			if s != "Paracetamol" {
+				b.Fatalf("took %q", s)
+			}
+		}
+	})
+}
+
+func TestFallback(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": pillCode + "\nconst Unknown Pill = -1\n", "color.go": colorCode})
+	runYamlenums(t, dir, "-type=Pill,Color", "-fallback=Unknown", "-acceptint", "-json", "-text", "-xml", "-gentests")
+	goTest(t, dir, `
+package painkiller
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestFallback(t *testing.T) {
+	for _, src := range []string{"Asprin", "7", "''"} {
+		p := Aspirin
+		if err := yaml.Unmarshal([]byte(src), &p); err != nil || p != Unknown {
+			t.Errorf("unmarshaling %s gives %v, %v", src, p, err)
+		}
+	}
+	var p Pill
+	if err := yaml.Unmarshal([]byte("Ibuprofen"), &p); err != nil || p != Ibuprofen {
+		t.Errorf("unmarshaling Ibuprofen gives %v, %v", p, err)
+	}
+	if err := yaml.Unmarshal([]byte("[Asprin]"), &p); err == nil {
+		t.Error("unmarshaling a sequence succeeded")
+	}
+	if err := json.Unmarshal([]byte("\"Asprin\""), &p); err != nil || p != Unknown {
+		t.Errorf("unmarshaling JSON gives %v, %v", p, err)
+	}
+	if err := p.UnmarshalText([]byte("Asprin")); err != nil || p != Unknown {
+		t.Errorf("unmarshaling text gives %v, %v", p, err)
+	}
+	if err := xml.Unmarshal([]byte("<pill>Asprin</pill>"), &p); err != nil || p != Unknown {
+		t.Errorf("unmarshaling XML gives %v, %v", p, err)
+	}
+	if _, err := ParsePill("Asprin"); err == nil {
+		t.Error("ParsePill(\"Asprin\") succeeded")
+	}
+	var c Color
+	if err := yaml.Unmarshal([]byte("Purple"), &c); err == nil {
+		t.Error("unmarshaling Purple succeeded for a type without a fallback")
+	}
+}
+`)
+	for _, tt := range []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"-type=Pill", "-fallback=Red"}, "the flag -fallback: Red is not a constant of Pill"},
+		{[]string{"-type=Pill,Color", "-fallback=Unknown,Placebo"}, "both Unknown and Placebo are constants of type Pill"},
+		{[]string{"-type=Pill", "-fallback=Unknown", "-bitflags"}, "the flag -fallback can't be used with -bitflags"},
+	} {
+		if out := runYamlenumsFail(t, dir, tt.args...); !strings.Contains(out, tt.err) {
+			t.Errorf("%v fails with:\n%s", tt.args, out)
+		}
+	}
+}
+
+func TestOverridable(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": pillCode})
+	runYamlenums(t, dir, "-type=Pill", "-overridable", "-json", "-stringer")
+	goTest(t, dir, `
+package painkiller
+
+import (
+	"encoding/json"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func init() {
+	PillMarshalMap = map[Pill]string{Aspirin: "Aspirina"}
+	PillUnmarshalMap = map[string]Pill{"Aspirina": Aspirin, "Placebo": Ibuprofen}
+}
+
+func TestOverridable(t *testing.T) {
+	out, err := yaml.Marshal([]Pill{Aspirin, Ibuprofen})
+	if err != nil || string(out) != "- Aspirina\n- Ibuprofen\n" {
+		t.Errorf("marshaling gives %q, %v", out, err)
+	}
+	if s := Aspirin.String(); s != "Aspirina" {
+		t.Errorf("Aspirin.String() = %q", s)
+	}
+	var ps []Pill
+	if err := yaml.Unmarshal([]byte("[Aspirina, Placebo, Paracetamol]"), &ps); err != nil ||
+		len(ps) != 3 || ps[0] != Aspirin || ps[1] != Ibuprofen || ps[2] != Paracetamol {
+		t.Errorf("unmarshaling gives %v, %v", ps, err)
+	}
+	if err := yaml.Unmarshal([]byte("Asprin"), new(Pill)); err == nil {
+		t.Error("unmarshaling Asprin succeeded")
+	}
+	var p Pill
+	if err := json.Unmarshal([]byte("\"Aspirina\""), &p); err != nil || p != Aspirin {
+		t.Errorf("unmarshaling JSON gives %v, %v", p, err)
+	}
+	if out, err := json.Marshal(Aspirin); err != nil || string(out) != "\"Aspirina\"" {
+		t.Errorf("marshaling JSON gives %s, %v", out, err)
+	}
+}
+`)
+	if out := runYamlenumsFail(t, dir, "-type=Pill", "-overridable", "-bitflags"); !strings.Contains(out, "the flag -overridable can't be used with -bitflags") {
+		t.Errorf("-overridable with -bitflags fails with:\n%s", out)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": pillCode})
+	runYamlenums(t, dir, "-type=Pill", "-validate")
+	goTest(t, dir, `
+package painkiller
+
+import "testing"
+
+func TestValidate(t *testing.T) {
+	if err := Acetaminophen.Validate(); err != nil {
+		t.Errorf("Acetaminophen.Validate() = %v", err)
+	}
+	if err := Pill(12).Validate(); err == nil || err.Error() != "invalid Pill: 12" {
+		t.Errorf("Pill(12).Validate() = %v", err)
+	}
+}
+`)
+	runYamlenums(t, dir, "-type=Pill", "-validate", "-errtype")
+	goTest(t, dir, `
+package painkiller
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	var invalid *InvalidPillError
+	if err := Pill(-12).Validate(); !errors.As(err, &invalid) || invalid.Value != "-12" {
+		t.Errorf("Pill(-12).Validate() = %v", err)
+	}
+}
+`)
+}
+
+func TestOutputDir(t *testing.T) {
+	dir := writePackage(t, map[string]string{
+		"pill.go":       pillCode,
+		"copy/pill.go":  pillCode,
+		"other/code.go": "package other\n",
+		"empty/code.go": "package painkiller\n",
+	})
+	runYamlenums(t, dir, "-type=Pill", "-dir=copy")
+	if _, err := os.Stat(filepath.Join(dir, "copy", "pill_yamlenums.go")); err != nil {
+		t.Errorf("no code generated in copy: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "pill_yamlenums.go")); !os.IsNotExist(err) {
+		t.Errorf("code generated in the parsed directory: %v", err)
+	}
+	if out := runYamlenumsFail(t, dir, "-type=Pill", "-dir=other"); !strings.Contains(out, "holds package other, not painkiller") {
+		t.Errorf("writing to other fails with:\n%s", out)
+	}
+	if out := runYamlenumsFail(t, dir, "-type=Pill", "-dir=empty"); !strings.Contains(out, "doesn't declare type Pill") {
+		t.Errorf("writing to empty fails with:\n%s", out)
+	}
+}
+
+func TestOrdinal(t *testing.T) {
+	dir := writePackage(t, map[string]string{"level.go": `
+package painkiller
+
+type Level int
+
+const (
+	Debug   Level = -4
+	Info    Level = 0
+	Warning Level = 4
+	Warn          = Warning
+	Error   Level = 8
+)
+`})
+	runYamlenums(t, dir, "-type=Level", "-ordinal")
+	goTest(t, dir, `
+package painkiller
+
+import "testing"
+
+func TestOrdinal(t *testing.T) {
+	for l, want := range map[Level]int{Debug: 0, Info: 1, Warning: 2, Error: 3, Level(1): -1} {
+		if i := l.Ordinal(); i != want {
+			t.Errorf("Level(%d).Ordinal() = %d, want %d", l, i, want)
+		}
+	}
+	if i := Warn.Ordinal(); i != 2 {
+		t.Errorf("Warn.Ordinal() = %d, want 2", i)
+	}
+	for i, want := range []Level{Debug, Info, Warning, Error} {
+		if l, err := LevelFromOrdinal(i); err != nil || l != want {
+			t.Errorf("LevelFromOrdinal(%d) = %d, %v", i, l, err)
+		}
+	}
+	for _, i := range []int{-1, 4} {
+		if _, err := LevelFromOrdinal(i); err == nil {
+			t.Errorf("LevelFromOrdinal(%d) succeeded", i)
+		}
+	}
+}
+`)
+}
+
+func TestNavigate(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": pillCode})
+	runYamlenums(t, dir, "-type=Pill", "-navigate")
+	goTest(t, dir, `
+package painkiller
+
+import "testing"
+
+func TestNavigate(t *testing.T) {
+	var got []Pill
+	for p, ok := Placebo, true; ok; p, ok = p.Next() {
+		got = append(got, p)
+	}
+	if len(got) != 4 || got[3] != Paracetamol {
+		t.Errorf("going forward visits %v", got)
+	}
+	got = nil
+	for p, ok := Acetaminophen, true; ok; p, ok = p.Prev() {
+		got = append(got, p)
+	}
+	if len(got) != 4 || got[3] != Placebo {
+		t.Errorf("going backward visits %v", got)
+	}
+	if p, ok := Placebo.Prev(); ok || p != Placebo {
+		t.Errorf("Placebo.Prev() = %d, %v", p, ok)
+	}
+	if p, ok := Pill(42).Next(); ok || p != 42 {
+		t.Errorf("Pill(42).Next() = %d, %v", p, ok)
+	}
+}
+`)
+}
+
+var permCode = `
+package painkiller
+
+type Perm uint
+
+const None Perm = 0
+
+const (
+	Read Perm = 1 << iota
+	Write
+	Exec
+)
+`
+
+func TestBitFlags(t *testing.T) {
+	dir := writePackage(t, map[string]string{"perm.go": permCode})
+	runYamlenums(t, dir, "-type=Perm", "-bitflags")
+	goTest(t, dir, `
+package painkiller
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestBitFlags(t *testing.T) {
+	b, err := yaml.Marshal(Read | Exec)
+	if err != nil || string(b) != "- Read\n- Exec\n" {
+		t.Errorf("yaml.Marshal(Read|Exec) = %q, %v", b, err)
+	}
+	var p Perm
+	if err := yaml.Unmarshal(b, &p); err != nil || p != Read|Exec {
+		t.Errorf("unmarshaling %q gives %d, %v", b, p, err)
+	}
+	if b, err := yaml.Marshal(None); err != nil || string(b) != "[]\n" {
+		t.Errorf("yaml.Marshal(None) = %q, %v", b, err)
+	}
+	if err := yaml.Unmarshal([]byte("[None, Write]"), &p); err != nil || p != Write {
+		t.Errorf("unmarshaling [None, Write] gives %d, %v", p, err)
+	}
+	if _, err := yaml.Marshal(Perm(9)); err == nil {
+		t.Error("marshaling Perm(9) succeeded")
+	}
+	for s, want := range map[string]string{
+		"Read":          "expected sequence for Perm, got scalar at line 1, column 1",
+		"[Read, Erase]": "invalid Perm \"Erase\" at line 1, column 8",
+		"[[Read]]":      "Perm should be a sequence of strings at line 1, column 2",
+	} {
+		if err := yaml.Unmarshal([]byte(s), &p); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("unmarshaling %s fails with %v", s, err)
+		}
+	}
+}
+`)
+	dir = writePackage(t, map[string]string{"perm.go": permCode})
+	runYamlenums(t, dir, "-type=Perm", "-bitflags", "-yamlpkg=v2")
+	cmd := exec.Command("go", "vet", ".")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("the code generated for v2 doesn't compile: %v\n%s", err, out)
+	}
+	dir = writePackage(t, map[string]string{"perm.go": permCode + "\nconst All Perm = 7\n"})
+	if out := runYamlenumsFail(t, dir, "-type=Perm", "-bitflags"); !strings.Contains(out, "All = 7 is not a power of two") {
+		t.Errorf("a combined constant fails with:\n%s", out)
+	}
+}
+
+func TestFlagString(t *testing.T) {
+	dir := writePackage(t, map[string]string{"perm.go": permCode})
+	runYamlenums(t, dir, "-type=Perm", "-bitflags", "-flagstring", "-transform=lower")
+	goTest(t, dir, `
+package painkiller
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestFlagString(t *testing.T) {
+	b, err := yaml.Marshal(Read | Write | Exec)
+	if err != nil || string(b) != "read|write|exec\n" {
+		t.Errorf("yaml.Marshal(Read|Write|Exec) = %q, %v", b, err)
+	}
+	for s, want := range map[string]Perm{"read|exec": Read | Exec, "exec | read": Read | Exec, "write": Write, "''": None} {
+		var p Perm
+		if err := yaml.Unmarshal([]byte(s), &p); err != nil || p != want {
+			t.Errorf("unmarshaling %s gives %d, %v", s, p, err)
+		}
+	}
+	if b, err := yaml.Marshal(None); err != nil || string(b) != "\"\"\n" {
+		t.Errorf("yaml.Marshal(None) = %q, %v", b, err)
+	}
+	var p Perm
+	if err := yaml.Unmarshal([]byte("read|erase"), &p); err == nil || !strings.Contains(err.Error(), "invalid Perm \"erase\" at line 1, column 1") {
+		t.Errorf("unmarshaling read|erase fails with %v", err)
+	}
+}
+`)
+	dir = writePackage(t, map[string]string{"perm.go": permCode})
+	runYamlenums(t, dir, "-type=Perm", "-bitflags", "-flagstring", "-flagsep=,", "-yamlpkg=v2")
+	cmd := exec.Command("go", "vet", ".")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("the code generated for v2 doesn't compile: %v\n%s", err, out)
+	}
+	if out := runYamlenumsFail(t, dir, "-type=Perm", "-bitflags", "-flagstring", "-flagsep=e"); !strings.Contains(out, "containing the separator") {
+		t.Errorf("a separator in a name fails with:\n%s", out)
+	}
+}
+
+func TestNameFunc(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": pillCode})
+	runYamlenums(t, dir, "-type=Pill", "-namefunc", "-transform=lower")
+	goTest(t, dir, `
+package painkiller
+
+import "testing"
+
+func TestNameFunc(t *testing.T) {
+	for v, want := range map[Pill]string{Placebo: "Placebo", Paracetamol: "Paracetamol", 42: ""} {
+		if s := PillName(v); s != want {
+			t.Errorf("PillName(%d) = %q, want %q", v, s, want)
+		}
+	}
+	if s := PillName(Acetaminophen); s != "Paracetamol" {
+		t.Errorf("PillName(Acetaminophen) = %q", s)
+	}
+}
+`)
+}
+
+func TestFileCase(t *testing.T) {
+	dir := writePackage(t, map[string]string{"status.go": `
+package painkiller
+
+type HTTPStatus int
+
+const OK HTTPStatus = 200
+`})
+	runYamlenums(t, dir, "-type=HTTPStatus")
+	runYamlenums(t, dir, "-type=HTTPStatus", "-filecase=snake", "-prefix=Gen_", "-yaml=false")
+	for _, name := range []string{"httpstatus_yamlenums.go", "gen_http_status_yamlenums.go"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s not generated: %v", name, err)
+		}
+	}
+	runYamlenumsFail(t, dir, "-type=HTTPStatus", "-filecase=kebab")
+}
+
+func TestSuffix(t *testing.T) {
+	for _, tc := range []struct {
+		args []string
+		file string
+	}{
+		{[]string{"-suffix=_gen"}, "pill_gen.go"},
+		{[]string{"-suffix=.enum"}, "pill.enum.go"},
+		{[]string{"-suffix=.Enum"}, "pill.enum.go"},
+		{[]string{"-suffix=_gen.go"}, "pill_gen.go"},
+		{[]string{"-suffix=.enum", "-gentests"}, "pill.enum_test.go"},
+		{[]string{"-suffix=", "-prefix=zz_"}, "zz_pill.go"},
+	} {
+		dir := writePackage(t, map[string]string{"painkiller.go": pillCode})
+		runYamlenums(t, dir, append([]string{"-type=Pill"}, tc.args...)...)
+		if _, err := os.Stat(filepath.Join(dir, tc.file)); err != nil {
+			t.Errorf("%s not generated with %v: %v", tc.file, tc.args, err)
+		}
+	}
+	dir := writePackage(t, map[string]string{"painkiller.go": pillCode})
+	for _, suffix := range []string{"-suffix=", "-suffix=.go", "-suffix=.GO"} {
+		if out := runYamlenumsFail(t, dir, "-type=Pill", suffix); !strings.Contains(out, "can't both be empty") {
+			t.Errorf("unexpected output with %s: %s", suffix, out)
+		}
+	}
+}
+
+func TestUnicodeNames(t *testing.T) {
+	dir := writePackage(t, map[string]string{"size.go": `
+package painkiller
+
+type Größe int
+
+const (
+	Klein Größe = iota
+	Groß
+	Übergroß
+)
+`})
+	runYamlenums(t, dir, "-type=Größe", "-ignorecase", "-stringer")
+	if _, err := os.Stat(filepath.Join(dir, "größe_yamlenums.go")); err != nil {
+		t.Errorf("größe_yamlenums.go not generated: %v", err)
+	}
+	goTest(t, dir, `
+package painkiller
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestUnicodeNames(t *testing.T) {
+	for _, v := range []Größe{Klein, Groß, Übergroß} {
+		b, err := yaml.Marshal(v)
+		if err != nil || string(b) != v.String()+"\n" {
+			t.Errorf("yaml.Marshal(%s) = %q, %v", v, b, err)
+		}
+		var g Größe
+		if err := yaml.Unmarshal(b, &g); err != nil || g != v {
+			t.Errorf("unmarshaling %q gives %v, %v", b, g, err)
+		}
+	}
+	var g Größe
+	if err := yaml.Unmarshal([]byte("ÜBERGROSS"), &g); err == nil {
+		t.Error("ß matches SS")
+	}
+	if err := yaml.Unmarshal([]byte("übergroß"), &g); err != nil || g != Übergroß {
+		t.Errorf("unmarshaling übergroß gives %v, %v", g, err)
+	}
+	if s := Übergroß.String(); s != "Übergroß" {
+		t.Errorf("Übergroß.String() = %q", s)
+	}
+}
+`)
+}
+
+func TestEscaping(t *testing.T) {
+	for _, lookup := range []string{"map", "switch", "binary"} {
+		t.Run(lookup, func(t *testing.T) {
+			dir := writePackage(t, map[string]string{"pill.go": `
+package painkiller
+
+type Pill int
+
+const (
+	Placebo Pill = iota // "sugar"\pill
+	Aspirin             // 100% \"acid\"
+)
+`})
+			runYamlenums(t, dir, "-type=Pill", "-linecomment", "-lookup="+lookup, "-ignorecase", "-stringer", "-errtype")
+			goTest(t, dir, `
+package painkiller
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestEscaping(t *testing.T) {
+	for v, want := range map[Pill]string{Placebo: "\"sugar\"\\pill", Aspirin: "100% \\\"acid\\\""} {
+		if s := v.String(); s != want {
+			t.Errorf("Pill(%d).String() = %q, want %q", v, s, want)
+		}
+		b, err := yaml.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var p Pill
+		if err := yaml.Unmarshal(b, &p); err != nil || p != v {
+			t.Errorf("unmarshaling %q gives %d, %v", b, p, err)
+		}
+	}
+	var p Pill
+	if err := yaml.Unmarshal([]byte("pill"), &p); err == nil || !strings.Contains(err.Error(), "100% \\\"acid\\\"") {
+		t.Errorf("unmarshaling pill fails with %v", err)
+	}
+}
+`)
+		})
+	}
+}
+
+func TestLicense(t *testing.T) {
+	dir := writePackage(t, map[string]string{
+		"pill.go":     pillCode,
+		"LICENSE.txt": "Copyright 2020 Painkiller Inc.\n\nAll rights reserved.\n",
+	})
+	runYamlenums(t, dir, "-type=Pill", "-license=LICENSE.txt", "-buildtag=!noyaml")
+	src, err := ioutil.ReadFile(filepath.Join(dir, "pill_yamlenums.go"))
+	must(t, err)
+	want := "// Copyright 2020 Painkiller Inc.\n//\n// All rights reserved.\n\n//go:build !noyaml\n"
+	if !strings.HasPrefix(string(src), want) {
+		t.Errorf("the generated file starts with:\n%s", src[:len(want)])
+	}
+	// The package doc stays empty, the header being no doc comment.
+	for tags, want := range map[string]string{
+		"":       "[pill.go pill_yamlenums.go] ",
+		"noyaml": "[pill.go] ",
+	} {
+		cmd := exec.Command("go", "list", "-tags="+tags, "-f", "{{.GoFiles}} {{.Doc}}", ".")
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("go list: %v\n%s", err, out)
+		}
+		if got := strings.TrimRight(string(out), "\n"); got != want {
+			t.Errorf("with tags %q the package has files and doc %q, want %q", tags, got, want)
+		}
+	}
+	runYamlenumsFail(t, dir, "-type=Pill", "-license=NOTICE.txt")
+}
+
+func TestExportMaps(t *testing.T) {
+	for _, lookup := range []string{"map", "switch", "binary"} {
+		t.Run(lookup, func(t *testing.T) {
+			dir := writePackage(t, map[string]string{"pill.go": pillCode})
+			runYamlenums(t, dir, "-type=Pill", "-exportmaps", "-lookup="+lookup, "-transform=lower")
+			goTest(t, dir, `
+package painkiller
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestExportMaps(t *testing.T) {
+	if len(PillNameToValue) != 5 || PillNameToValue["acetaminophen"] != Paracetamol {
+		t.Errorf("PillNameToValue = %v", PillNameToValue)
+	}
+	want := map[Pill]string{Placebo: "placebo", Aspirin: "aspirin", Ibuprofen: "ibuprofen", Paracetamol: "paracetamol"}
+	if fmt.Sprint(PillValueToName) != fmt.Sprint(want) {
+		t.Errorf("PillValueToName = %v, want %v", PillValueToName, want)
+	}
+}
+`)
+		})
+	}
+}
+
+func TestSchema(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": pillCode, "color.go": colorCode, "perm.go": permCode})
+	runYamlenums(t, dir, "-type=Pill,Color", "-single", "-schema", "-transform=lower")
+	runYamlenums(t, dir, "-type=Perm", "-schema", "-bitflags")
+	for name, want := range map[string]string{
+		"pill.schema.json": `{
+  "title": "Pill",
+  "type": "string",
+  "enum": [
+    "placebo",
+    "aspirin",
+    "ibuprofen",
+    "paracetamol",
+    "acetaminophen"
+  ]
+}
+`,
+		"color.schema.json": `{
+  "title": "Color",
+  "type": "string",
+  "enum": [
+    "red",
+    "green",
+    "blue"
+  ]
+}
+`,
+		"perm.schema.json": `{
+  "title": "Perm",
+  "type": "array",
+  "items": {
+    "type": "string",
+    "enum": [
+      "None",
+      "Read",
+      "Write",
+      "Exec"
+    ]
+  }
+}
+`,
+	} {
+		src, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Error(err)
+		} else if string(src) != want {
+			t.Errorf("%s holds:\n%s\nwant:\n%s", name, src, want)
+		}
+	}
+	if out := runYamlenums(t, dir, "-type=Color", "-schema", "-output=-", "-pkg=other"); !strings.Contains(out, `"title": "Color"`) {
+		t.Errorf("-output=- writes:\n%s", out)
+	}
+	if out := runYamlenumsFail(t, dir, "-type=Pill", "-schema", "-marshalint"); !strings.Contains(out, "the flag -schema can't describe the integers of -marshalint") {
+		t.Errorf("-schema with -marshalint fails with:\n%s", out)
+	}
+}
+
+func TestAliasType(t *testing.T) {
+	dir := writePackage(t, map[string]string{
+		"meds/pill.go": strings.Replace(pillCode, "package painkiller", "package meds", 1),
+		"pill.go": `
+package painkiller
+
+import "example.com/painkiller/meds"
+
+type Pill = meds.Pill
+
+const Aspirin Pill = meds.Aspirin
+`,
+	})
+	if out := runYamlenumsFail(t, dir, "-type=Pill"); !strings.Contains(out, "cannot generate methods for alias type Pill of meds.Pill") {
+		t.Errorf("an alias type fails with:\n%s", out)
+	}
+}
+
+func TestKeepGoing(t *testing.T) {
+	files := map[string]string{"pill.go": pillCode, "color.go": colorCode + "\ntype Empty int\n"}
+	for _, tt := range []struct {
+		args      []string
+		generated []string
+	}{
+		{nil, nil},
+		{[]string{"-keepgoing"}, []string{"pill_yamlenums.go", "color_yamlenums.go"}},
+		{[]string{"-keepgoing", "-single"}, []string{"pill_yamlenums.go"}},
+	} {
+		dir := writePackage(t, files)
+		out := runYamlenumsFail(t, dir, append([]string{"-type=Pill,Empty,Color"}, tt.args...)...)
+		if !strings.Contains(out, "type Empty found but has no constants") {
+			t.Errorf("with %v yamlenums logs:\n%s", tt.args, out)
+		}
+		names, err := filepath.Glob(filepath.Join(dir, "*_yamlenums.go"))
+		must(t, err)
+		for i := range names {
+			names[i] = filepath.Base(names[i])
+		}
+		sort.Strings(names)
+		want := append([]string(nil), tt.generated...)
+		sort.Strings(want)
+		if fmt.Sprint(names) != fmt.Sprint(want) {
+			t.Errorf("with %v yamlenums generates %v, want %v", tt.args, names, want)
+		}
+	}
+	dir := writePackage(t, files)
+	if out := runYamlenumsFail(t, dir, "-type=Empty", "-keepgoing"); !strings.Contains(out, "no constants") {
+		t.Errorf("with no type left yamlenums logs:\n%s", out)
+	}
+}
+
+func TestTypeFile(t *testing.T) {
+	dir := writePackage(t, map[string]string{
+		"pill.go":  pillCode,
+		"color.go": colorCode,
+		"types.txt": `# The enums of the package.
+Pill
+
+Color # primary colors
+`,
+		"missing.txt": "Pill\nPil\nColour\n",
+	})
+	runYamlenums(t, dir, "-type=Color", "-typefile=types.txt")
+	for _, name := range []string{"pill_yamlenums.go", "color_yamlenums.go"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s not generated: %v", name, err)
+		}
+	}
+	if out := runYamlenumsFail(t, dir, "-typefile=missing.txt"); !strings.Contains(out, "types Pil, Colour not found in package painkiller") {
+		t.Errorf("missing types fail with:\n%s", out)
+	}
+	runYamlenumsFail(t, dir, "-typefile=types.txt", "-all-types")
+}
+
+func TestTypeListCommas(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": pillCode, "color.go": colorCode})
+	runYamlenums(t, dir, "-type=,Pill,,Color,")
+	for _, name := range []string{"pill_yamlenums.go", "color_yamlenums.go"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s not generated: %v", name, err)
+		}
+	}
+	if out := runYamlenumsFail(t, dir, "-type=,,"); !strings.Contains(out, "lists no types") {
+		t.Errorf("a list of commas fails with:\n%s", out)
+	}
+}
+
+func TestQualifiedTypes(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": pillCode, "color.go": colorCode})
+	out := runYamlenums(t, dir, "-type=painkiller.Pill,Color,Pill", "-dryrun")
+	if n := strings.Count(out, "would write"); n != 2 {
+		t.Errorf("%d files would be written:\n%s", n, out)
+	}
+	out = runYamlenumsFail(t, dir, "-type=Color,medicine.Pill")
+	if !strings.Contains(out, "type medicine.Pill is qualified by package medicine, but the package parsed is painkiller") {
+		t.Errorf("a mismatched qualifier fails with:\n%s", out)
+	}
+}
+
+func TestPerm(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": pillCode})
+	path := filepath.Join(dir, "pill_yamlenums.go")
+	for _, args := range [][]string{{"-perm=0444"}, {"-perm=0444", "-stringer"}, {"-perm=0640", "-stringer"}} {
+		runYamlenums(t, dir, append([]string{"-type=Pill"}, args...)...)
+		info, err := os.Stat(path)
+		must(t, err)
+		want := args[0][len("-perm="):]
+		if got := fmt.Sprintf("%04o", info.Mode().Perm()); got != want {
+			t.Errorf("the mode with %v is %s, want %s", args, got, want)
+		}
+	}
+	for _, perm := range []string{"0999", "01777", "0044", "rw-r--r--"} {
+		if out := runYamlenumsFail(t, dir, "-type=Pill", "-perm="+perm); !strings.Contains(out, "the flag -perm") {
+			t.Errorf("-perm=%s fails with:\n%s", perm, out)
+		}
+	}
+
+	// Without -perm, rewritten files keep their mode, read-only or not, and
+	// symlinks are written through.
+	target := filepath.Join(dir, "target.txt")
+	must(t, os.Remove(path))
+	must(t, os.Symlink(target, path))
+	for _, mode := range []os.FileMode{0600, 0444} {
+		must(t, ioutil.WriteFile(target, []byte("package painkiller\n"), mode))
+		must(t, os.Chmod(target, mode))
+		runYamlenums(t, dir, "-type=Pill")
+		info, err := os.Lstat(path)
+		must(t, err)
+		if info.Mode()&os.ModeSymlink == 0 {
+			t.Errorf("the symlink was replaced by a file with mode %s", info.Mode())
+		}
+		info, err = os.Stat(target)
+		must(t, err)
+		if info.Mode().Perm() != mode {
+			t.Errorf("the mode %04o became %04o", mode, info.Mode().Perm())
+		}
+		if b, err := ioutil.ReadFile(target); err != nil || !strings.Contains(string(b), "func (r Pill) MarshalYAML") {
+			t.Errorf("the symlink target wasn't written: %v\n%s", err, b)
+		}
+		must(t, os.Remove(target))
+	}
+}
+
+func TestDuplicateTypes(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": pillCode, "color.go": colorCode})
+	out := runYamlenums(t, dir, "-type=Pill,Color,Pill", "-dryrun")
+	if n := strings.Count(out, "would write"); n != 2 {
+		t.Errorf("%d files would be written:\n%s", n, out)
+	}
+	if i, j := strings.Index(out, "pill_yamlenums.go"), strings.Index(out, "color_yamlenums.go"); i < 0 || j < i {
+		t.Errorf("the types aren't generated in the order first listed:\n%s", out)
+	}
+}
+
+func TestInterfaceChecks(t *testing.T) {
+	dir := writePackage(t, map[string]string{"env.go": `
+package painkiller
+
+type Env string
+
+const Prod Env = "production"
+`})
+	runYamlenums(t, dir, "-type=Env", "-json", "-text", "-sql", "-flagvalue")
+	src, err := ioutil.ReadFile(filepath.Join(dir, "env_yamlenums.go"))
+	must(t, err)
+	for _, check := range []string{
+		`_ fmt.Stringer\s+= Env\(""\)`,
+		`_ yaml.Marshaler\s+= Env\(""\)`,
+		`_ yaml.Unmarshaler\s+= \(\*Env\)\(nil\)`,
+		`_ json.Marshaler\s+= Env\(""\)`,
+		`_ encoding.TextUnmarshaler\s+= \(\*Env\)\(nil\)`,
+		`_ driver.Valuer\s+= Env\(""\)`,
+		`_ sql.Scanner\s+= \(\*Env\)\(nil\)`,
+		`_ flag.Value\s+= \(\*Env\)\(nil\)`,
+	} {
+		if !regexp.MustCompile(check).Match(src) {
+			t.Errorf("no check matching %s", check)
+		}
+	}
+}
+
+func TestNoFormat(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": pillCode})
+	formatted := runYamlenums(t, dir, "-type=Pill", "-output=-", "-stringer")
+	raw := runYamlenums(t, dir, "-type=Pill", "-output=-", "-stringer", "-noformat")
+	if raw == formatted {
+		t.Fatal("-noformat makes no difference")
+	}
+	src, err := format.Source([]byte(raw))
+	must(t, err)
+	// The headers differ by -noformat.
+	body := func(src string) string { return src[strings.Index(src, "\n"):] }
+	if body(string(src)) != body(formatted) {
+		t.Errorf("formatting the code of -noformat gives:\n%s\nwant:\n%s", src, formatted)
+	}
+	runYamlenums(t, dir, "-type=Pill", "-noformat", "-stringer")
+	goTest(t, dir, "package painkiller\n")
+}
+
+func TestFixImports(t *testing.T) {
+	dir := writePackage(t, map[string]string{
+		"pill.go": pillCode,
+		"upper.tmpl": `
+// Code generated by yamlenums; DO NOT EDIT.
+
+package {{.PackageName}}
+
+import "os"
+{{range $typename, $values := .TypesAndValues}}
+func Upper{{$typename}}(v {{$typename}}) string {
+	return strings.ToUpper(fmt.Sprint(int(v)))
+}
+{{end}}
+`,
+	})
+	tmpl := "-template=" + filepath.Join(dir, "upper.tmpl")
+	out := runYamlenums(t, dir, "-type=Pill", tmpl, "-fiximports", "-output=-")
+	if !strings.Contains(out, `"strings"`) || !strings.Contains(out, `"fmt"`) || strings.Contains(out, `"os"`) {
+		t.Errorf("imports not fixed:\n%s", out)
+	}
+	runYamlenums(t, dir, "-type=Pill", tmpl, "-fiximports")
+	goTest(t, dir, "package painkiller\n")
+	runYamlenumsFail(t, dir, "-type=Pill", "-fiximports", "-noformat")
+}
+
+func TestBestEffort(t *testing.T) {
+	dir := writePackage(t, map[string]string{
+		"pill.go":   pillCode,
+		"broken.go": "package painkiller\n\nvar broken int = \"broken\"\n",
+	})
+	runYamlenumsFail(t, dir, "-type=Pill")
+	if out := runYamlenums(t, dir, "-type=Pill", "-best-effort", "-verbose"); !strings.Contains(out, "ignoring error") {
+		t.Errorf("the error isn't logged:\n%s", out)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "pill_yamlenums.go")); err != nil {
+		t.Errorf("pill_yamlenums.go not generated: %v", err)
+	}
+}
+
+func TestExample(t *testing.T) {
+	dir := writePackage(t, map[string]string{"pill.go": `
+package painkiller
+
+type Pill int
+
+const (
+	Placebo Pill = iota
+	Aspirin // yes
+	Ibuprofen
+)
+`})
+	runYamlenums(t, dir, "-type=Pill", "-example", "-linecomment")
+	src, err := ioutil.ReadFile(filepath.Join(dir, "pill_yamlenums_example_test.go"))
+	must(t, err)
+	// yes needs quoting, as YAML 1.1 takes it for true.
+	if want := "// Output:\n\t// Placebo\n\t// true\n\t// \"yes\"\n\t// true\n}"; !strings.Contains(string(src), want) {
+		t.Errorf("the example has no output %q:\n%s", want, src)
+	}
+	goTest(t, dir, "package painkiller\n")
+
+	dir = writePackage(t, map[string]string{"pill.go": pillCode})
+	runYamlenums(t, dir, "-type=Pill", "-example", "-yamltag=!pill", "-ptrmarshal", "-stringer")
+	// The String method generated before doesn't count as one of its own.
+	runYamlenums(t, dir, "-type=Pill", "-example", "-yamltag=!pill", "-ptrmarshal", "-stringer")
+	goTest(t, dir, "package painkiller\n")
+
+	dir = writePackage(t, map[string]string{"pill.go": pillCode + "\nfunc (p Pill) String() string { return \"pill\" }\n"})
+	if out := runYamlenumsFail(t, dir, "-type=Pill", "-example"); !strings.Contains(out, "String method of Pill") {
+		t.Errorf("a String method fails with:\n%s", out)
+	}
+}